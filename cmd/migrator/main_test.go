@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    command
+		wantErr string
+	}{
+		{name: "up", args: []string{"up"}, want: command{name: "up"}},
+		{name: "version", args: []string{"version"}, want: command{name: "version"}},
+		{name: "down", args: []string{"down", "2"}, want: command{name: "down", arg: 2}},
+		{name: "goto", args: []string{"goto", "0"}, want: command{name: "goto"}},
+		{name: "force none", args: []string{"force", "-1"}, want: command{name: "force", arg: -1}},
+		{name: "missing", args: nil, wantErr: "missing command"},
+		{name: "unknown", args: []string{"sideways"}, wantErr: `unknown command "sideways"`},
+		{name: "up with argument", args: []string{"up", "3"}, wantErr: "up takes no arguments"},
+		{name: "down without N", args: []string{"down"}, wantErr: "down takes exactly one argument"},
+		{name: "goto not a number", args: []string{"goto", "x"}, wantErr: `goto: "x" is not a number`},
+		{name: "down zero", args: []string{"down", "0"}, wantErr: "down: N must be at least 1"},
+		{name: "goto negative", args: []string{"goto", "-1"}, wantErr: "goto: V must not be negative"},
+		{name: "force below none", args: []string{"force", "-2"}, wantErr: "force: V must be a version or -1 for none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := parseCommand(tt.args)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cmd)
+		})
+	}
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+
+	// A migration that fails halfway leaves its version dirty.
+	broken := filepath.Join(dir, "broken")
+	require.NoError(t, os.Mkdir(broken, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(broken, "1_broken.up.sql"), []byte("CREATE TABLE t (id INTEGER); NOT SQL;"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(broken, "1_broken.down.sql"), []byte("DROP TABLE t;"), 0o600))
+
+	sqliteDSN := func(name string) string {
+		return "sqlite://" + filepath.Join(dir, name)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "help", args: []string{"--help"}, want: exitOK},
+		{name: "unknown flag", args: []string{"--bogus", "up"}, want: exitUsage},
+		{name: "missing command", args: nil, want: exitUsage, wantStderr: "missing command"},
+		{name: "invalid command", args: []string{"down", "0"}, want: exitUsage, wantStderr: "N must be at least 1"},
+		{
+			name:       "missing config file",
+			args:       []string{"--config", filepath.Join(dir, "missing.yaml"), "up"},
+			want:       exitFailed,
+			wantStderr: "config path does not exist",
+		},
+		{
+			name:       "driver without migrations",
+			args:       []string{"--config", "../../tests/config/memory.yaml", "up"},
+			want:       exitUsage,
+			wantStderr: `storage driver "memory" has no migrations`,
+		},
+		{name: "version of empty database", args: []string{"--dsn", sqliteDSN("empty.db"), "version"}, want: exitOK, wantStdout: "version: none"},
+		{name: "up", args: []string{"--dsn", sqliteDSN("up.db"), "up"}, want: exitOK, wantStdout: "version: "},
+		{name: "failed migration", args: []string{"--dsn", sqliteDSN("broken.db"), "--path", broken, "up"}, want: exitFailed},
+		{
+			name:       "dirty database",
+			args:       []string{"--dsn", sqliteDSN("broken.db"), "--path", broken, "up"},
+			want:       exitDirty,
+			wantStderr: "version 1 is dirty",
+		},
+	}
+
+	// The cases run in order: the dirty database is the one the failed
+	// migration left behind.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := run(tt.args, &stdout, &stderr)
+
+			assert.Equal(t, tt.want, code, "stdout: %s\nstderr: %s", &stdout, &stderr)
+			assert.Contains(t, stdout.String(), tt.wantStdout)
+			assert.Contains(t, stderr.String(), tt.wantStderr)
+		})
+	}
+}
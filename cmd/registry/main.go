@@ -0,0 +1,118 @@
+// Command registry reconciles the stored apps with a registry file and
+// prints the changes, one per line. Each line starts with + for a created,
+// ~ for an updated and - for a deleted app, followed by its ID and name and,
+// for updates, the fields that changed, e.g. ~ app 1 "test": token_ttl.
+//
+// With --dry-run it only prints what it would change. The file, and whether
+// apps it does not list are deleted, default to the registry section of the
+// config.
+//
+// The exit code is 0 on success, 1 if the config or registry file cannot be
+// read or reconciling failed and 2 if the command line is invalid.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sso/internal/app"
+	"sso/internal/config"
+	"sso/internal/services/registry"
+)
+
+const (
+	exitOK = iota
+	exitFailed
+	exitUsage
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("registry", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var (
+		configPath = flags.String("config", "config/local.yaml", "config file")
+		path       = flags.String("file", "", "registry file (default: registry.path of the config)")
+		prune      = flags.Bool("prune", false, "delete apps the file does not list (default: registry.prune of the config)")
+		dryRun     = flags.Bool("dry-run", false, "print the changes without applying them")
+	)
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "registry: unexpected arguments:", flags.Args())
+		return exitUsage
+	}
+
+	cfg, err := config.LoadPath(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "registry:", err)
+		return exitFailed
+	}
+
+	if *path == "" {
+		*path = cfg.Registry.Path
+	}
+	if *path == "" {
+		fmt.Fprintln(stderr, "registry: no registry file, set --file or registry.path")
+		return exitUsage
+	}
+
+	pruneSet := false
+	flags.Visit(func(f *flag.Flag) {
+		pruneSet = pruneSet || f.Name == "prune"
+	})
+	if !pruneSet {
+		*prune = cfg.Registry.Prune
+	}
+
+	file, err := registry.Load(*path)
+	if err != nil {
+		fmt.Fprintln(stderr, "registry:", err)
+		return exitFailed
+	}
+
+	storage, err := app.NewStorage(cfg.Storage, cfg.Encryption)
+	if err != nil {
+		fmt.Fprintln(stderr, "registry:", err)
+		return exitFailed
+	}
+	defer storage.Close()
+
+	log := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	changes, err := registry.New(log, storage, cfg.Signing.SecretGracePeriod).
+		Reconcile(context.Background(), file, registry.Options{Prune: *prune, DryRun: *dryRun})
+
+	for _, change := range changes {
+		fmt.Fprintln(stdout, change)
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "registry:", err)
+		return exitFailed
+	}
+
+	switch {
+	case len(changes) == 0:
+		fmt.Fprintln(stdout, "no changes")
+	case *dryRun:
+		fmt.Fprintf(stdout, "%d changes not applied (dry run)\n", len(changes))
+	default:
+		fmt.Fprintf(stdout, "%d changes applied\n", len(changes))
+	}
+
+	return exitOK
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("storage: [\n"), 0o600))
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStderr string
+	}{
+		{name: "help", args: []string{"--help"}, want: exitOK},
+		{name: "unknown flag", args: []string{"--bogus"}, want: exitUsage},
+		{name: "unexpected arguments", args: []string{"apply"}, want: exitUsage, wantStderr: "unexpected arguments"},
+		{
+			name:       "missing config file",
+			args:       []string{"--config", filepath.Join(dir, "missing.yaml")},
+			want:       exitFailed,
+			wantStderr: "config path does not exist",
+		},
+		{
+			name:       "invalid config file",
+			args:       []string{"--config", invalid},
+			want:       exitFailed,
+			wantStderr: "failed to read config",
+		},
+		{
+			name:       "no registry file",
+			args:       []string{"--config", "../../tests/config/sqlite.yaml"},
+			want:       exitUsage,
+			wantStderr: "no registry file",
+		},
+		{
+			name:       "missing registry file",
+			args:       []string{"--config", "../../tests/config/sqlite.yaml", "--file", filepath.Join(dir, "apps.yaml")},
+			want:       exitFailed,
+			wantStderr: "registry:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := run(tt.args, &stdout, &stderr)
+
+			assert.Equal(t, tt.want, code, "stdout: %s\nstderr: %s", &stdout, &stderr)
+			assert.Contains(t, stderr.String(), tt.wantStderr)
+		})
+	}
+}
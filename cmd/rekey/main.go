@@ -0,0 +1,87 @@
+// Command rekey re-wraps data keys with the current master key and encrypts
+// app secrets and signing keys that are still stored in plaintext.
+//
+// To rotate the master key, configure the new key as master_key (or
+// master_key_file), move the old one to previous_master_keys, run rekey and
+// then remove the old key from the configuration.
+//
+// The exit code is 0 on success and 1 if rekeying failed.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sso/internal/config"
+	"sso/internal/lib/envelope"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage/postgres"
+	"sso/internal/storage/sqlite"
+)
+
+// rekeyer is implemented by the storage backends that encrypt at rest.
+type rekeyer interface {
+	Rekey(ctx context.Context) (rewrapped int64, encrypted int64, err error)
+	Close() error
+}
+
+const (
+	exitOK = iota
+	exitFailed
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run rekeys the configured storage and returns the exit code. It returns
+// rather than exits so that the storage is closed first.
+func run() int {
+	cfg := config.MustLoad()
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	keyring, err := envelope.LoadKeyring(cfg.Encryption.MasterKey, cfg.Encryption.MasterKeyFile, cfg.Encryption.PreviousMasterKeys)
+	if err != nil {
+		log.Error("failed to load master keys", sl.Err(err))
+		return exitFailed
+	}
+
+	storage, err := openStorage(cfg.Storage, keyring)
+	if err != nil {
+		log.Error("failed to open storage", sl.Err(err))
+		return exitFailed
+	}
+	defer storage.Close()
+
+	rewrapped, encrypted, err := storage.Rekey(context.Background())
+	if err != nil {
+		log.Error("failed to rekey", sl.Err(err))
+		return exitFailed
+	}
+
+	log.Info("rekey complete",
+		slog.String("master_key_id", keyring.PrimaryID()),
+		slog.Int64("data_keys_rewrapped", rewrapped),
+		slog.Int64("values_encrypted", encrypted),
+	)
+
+	return exitOK
+}
+
+func openStorage(cfg config.StorageConfig, keyring *envelope.Keyring) (rekeyer, error) {
+	switch cfg.Driver {
+	case config.StorageDriverPostgres:
+		dsn, err := cfg.URL()
+		if err != nil {
+			return nil, err
+		}
+
+		return postgres.New(dsn, postgres.PoolConfig{MaxOpenConns: 1, MaxIdleConns: 1}, keyring)
+	case config.StorageDriverSQLite:
+		return sqlite.New(cfg.Path, keyring)
+	default:
+		return nil, fmt.Errorf("storage driver %q keeps nothing to rekey", cfg.Driver)
+	}
+}
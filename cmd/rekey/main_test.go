@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	"sso/internal/lib/envelope"
+	"sso/internal/storage/schema"
+	"sso/internal/storage/sqlite"
+	"testing"
+)
+
+func TestRekey(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := config.StorageConfig{
+		Driver: config.StorageDriverSQLite,
+		Path:   filepath.Join(t.TempDir(), "sso.db"),
+	}
+	_, err := schema.UpSQLite(cfg.Path)
+	require.NoError(t, err)
+
+	oldMaster, newMaster := newKey(t), newKey(t)
+
+	// App 1 is encrypted under the old master key, app 2 is a plaintext row
+	// written before encryption was introduced.
+	oldKeyring, err := envelope.NewKeyring(oldMaster)
+	require.NoError(t, err)
+
+	st, err := sqlite.New(cfg.Path, oldKeyring)
+	require.NoError(t, err)
+	require.NoError(t, st.SaveApp(ctx, models.App{ID: 1, Name: "encrypted", Secret: "encrypted-secret"}))
+	require.NoError(t, st.Close())
+
+	db, err := sql.Open("sqlite", cfg.Path)
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO apps (id, name) VALUES (2, 'plaintext')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO app_secrets (app_id, secret, state, created_at) VALUES (2, CAST('plaintext-secret' AS BLOB), 'active', 0)")
+	require.NoError(t, err)
+
+	keyring, err := envelope.NewKeyring(newMaster, oldMaster)
+	require.NoError(t, err)
+
+	storage, err := openStorage(cfg, keyring)
+	require.NoError(t, err)
+
+	rewrapped, encrypted, err := storage.Rekey(ctx)
+	require.NoError(t, err)
+	require.NoError(t, storage.Close())
+	assert.EqualValues(t, 1, rewrapped)
+	assert.EqualValues(t, 1, encrypted)
+
+	var plaintextRows int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM app_secrets WHERE data_key_id IS NULL").Scan(&plaintextRows))
+	assert.Zero(t, plaintextRows)
+
+	var secret []byte
+	require.NoError(t, db.QueryRow("SELECT secret FROM app_secrets WHERE app_id = 2").Scan(&secret))
+	assert.NotEqual(t, "plaintext-secret", string(secret))
+	require.NoError(t, db.Close())
+
+	// Both secrets can be read without the old master key.
+	newOnly, err := envelope.NewKeyring(newMaster)
+	require.NoError(t, err)
+
+	st, err = sqlite.New(cfg.Path, newOnly)
+	require.NoError(t, err)
+	defer st.Close()
+
+	for appID, want := range map[int64]string{1: "encrypted-secret", 2: "plaintext-secret"} {
+		secrets, err := st.AppSecrets(ctx, appID)
+		require.NoError(t, err)
+		require.Len(t, secrets, 1)
+		assert.Equal(t, want, secrets[0].Secret)
+	}
+}
+
+func TestOpenStorage_Memory(t *testing.T) {
+	keyring, err := envelope.NewKeyring(newKey(t))
+	require.NoError(t, err)
+
+	_, err = openStorage(config.StorageConfig{Driver: config.StorageDriverMemory}, keyring)
+	assert.Error(t, err)
+}
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := envelope.NewDataKey()
+	require.NoError(t, err)
+
+	return key
+}
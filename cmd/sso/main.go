@@ -6,6 +6,7 @@ import (
 	"os/signal"
 	"sso/internal/app"
 	"sso/internal/config"
+	"sso/internal/lib/logger/sl"
 	"syscall"
 )
 
@@ -20,11 +21,22 @@ func main() {
 
 	log := setupLogger(cfg.Env)
 
-	log.Info("start app", cfg)
+	// The config holds secrets such as the master key and passwords, so only
+	// fields that are safe to log are.
+	log.Info("start app",
+		slog.String("env", cfg.Env),
+		slog.Int("grpc_port", cfg.GRPC.Port),
+		slog.Int("http_port", cfg.HTTP.Port),
+		slog.String("storage_driver", cfg.Storage.Driver),
+		slog.String("signing_algorithm", cfg.Signing.Algorithm),
+		slog.String("mail_driver", cfg.Mail.Driver),
+	)
 
-	application := app.New(log, cfg.GRPC.Port, cfg.TokenTTL)
+	application := app.New(log, cfg)
 
 	go application.GRPCsrv.MustRun()
+	go application.HTTPsrv.MustRun()
+	go application.KeyRotator.Run()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
@@ -32,6 +44,12 @@ func main() {
 	sig := <-stop
 
 	application.GRPCsrv.Stop()
+	application.HTTPsrv.Stop()
+	application.KeyRotator.Stop()
+	application.Auth.Wait()
+	if err := application.Storage.Close(); err != nil {
+		log.Error("failed to close storage", sl.Err(err))
+	}
 	log.Info("stop app: ", sig)
 
 }
@@ -1,28 +1,166 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"log/slog"
+	"net/http"
 	grpcapp "sso/internal/app/grpc"
+	httpapp "sso/internal/app/http"
+	"sso/internal/config"
+	"sso/internal/http/introspect"
+	"sso/internal/http/wellknown"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/mailer"
+	"sso/internal/services/admin"
 	"sso/internal/services/auth"
-	"sso/internal/storage/postgres"
-	"time"
+	"sso/internal/services/keys"
+	"sso/internal/services/rbac"
+	"sso/internal/services/registry"
 )
 
 type App struct {
-	GRPCsrv *grpcapp.App
+	GRPCsrv    *grpcapp.App
+	HTTPsrv    *httpapp.App
+	KeyRotator *keys.Rotator
+	Auth       *auth.Auth
+	Storage    Storage
 }
 
-func New(log *slog.Logger, grpcPort int, tokenTTL time.Duration) *App {
-	storage, err := postgres.New()
+func New(log *slog.Logger, cfg *config.Config) *App {
+	if cfg.Storage.AutoMigrate {
+		if err := migrateSchema(context.Background(), log, cfg.Storage); err != nil {
+			panic(err)
+		}
+	}
+
+	storage, err := NewStorage(cfg.Storage, cfg.Encryption)
+	if err != nil {
+		panic(err)
+	}
+
+	if cfg.Registry.Path != "" {
+		if err := reconcileApps(context.Background(), log, storage, cfg); err != nil {
+			panic(err)
+		}
+	}
+
+	keyService, err := keys.New(log, storage, storage, cfg.Signing.Algorithm, cfg.Signing.RotationInterval, cfg.TokenTTL)
+	if err != nil {
+		panic(err)
+	}
+
+	relyingParty, err := newRelyingParty(cfg.WebAuthn)
 	if err != nil {
 		panic(err)
 	}
 
-	authService := auth.NewAuth(log, storage, storage, storage, tokenTTL)
+	mail, err := newMailer(log, cfg.Mail)
+	if err != nil {
+		panic(err)
+	}
+
+	authService := auth.NewAuth(
+		log,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		keyService,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		mail,
+		cfg.TokenTTL,
+		cfg.RefreshTokenTTL,
+		cfg.Session.TTL,
+		cfg.Session.IdleTimeout,
+		jwt.Options{Issuer: cfg.Tokens.Issuer, ClaimsVersion: cfg.Tokens.ClaimsVersion},
+		auth.MFAOptions{
+			Issuer:       cfg.MFA.Issuer,
+			ChallengeTTL: cfg.MFA.ChallengeTTL,
+			MaxAttempts:  cfg.MFA.MaxAttempts,
+			MaxFailures:  cfg.MFA.MaxFailures,
+			Lockout:      cfg.MFA.Lockout,
+		},
+		auth.PasskeyOptions{RelyingParty: relyingParty, CeremonyTTL: cfg.WebAuthn.CeremonyTTL},
+		auth.VerificationOptions{TTL: cfg.Mail.VerificationTTL, URL: cfg.Mail.VerificationURL},
+		auth.PasswordResetOptions{TTL: cfg.Mail.PasswordResetTTL, URL: cfg.Mail.PasswordResetURL},
+		auth.PasswordOptions{HashCost: cfg.Password.HashCost, StepUpMaxAge: cfg.Password.StepUpMaxAge},
+	)
+
+	rbacService := rbac.New(log, storage)
+	adminService := admin.New(log, storage, storage, cfg.Signing.SecretGracePeriod)
+
+	grpcApp := grpcapp.New(log, authService, keyService, rbacService, adminService, authService, cfg.GRPC.Port)
 
-	grpcApp := grpcapp.New(log, authService, grpcPort)
+	mux := http.NewServeMux()
+	wellknown.Register(mux, log, keyService)
+	introspect.Register(mux, log, authService)
+
+	httpApp := httpapp.New(log, mux, cfg.HTTP.Port, cfg.HTTP.Timeout)
 
 	return &App{
-		GRPCsrv: grpcApp,
+		GRPCsrv:    grpcApp,
+		HTTPsrv:    httpApp,
+		KeyRotator: keys.NewRotator(log, keyService, cfg.Signing.RotationCheckInterval),
+		Auth:       authService,
+		Storage:    storage,
+	}
+}
+
+// newRelyingParty returns the relying party that verifies passkeys, or nil if
+// passkeys are not configured.
+func newRelyingParty(cfg config.WebAuthnConfig) (*webauthn.WebAuthn, error) {
+	if cfg.RPID == "" {
+		return nil, nil
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+	})
+}
+
+// newMailer returns the mailer selected by cfg.
+func newMailer(log *slog.Logger, cfg config.MailConfig) (auth.Mailer, error) {
+	switch cfg.Driver {
+	case config.MailDriverSMTP:
+		return mailer.NewSMTP(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.From), nil
+	case config.MailDriverLog:
+		return mailer.NewLog(log), nil
+	case config.MailDriverFile:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("mail dir is required for the %q driver", config.MailDriverFile)
+		}
+
+		return mailer.NewFile(cfg.Dir, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
 	}
 }
+
+// reconcileApps makes the stored apps match the registry file.
+func reconcileApps(ctx context.Context, log *slog.Logger, storage Storage, cfg *config.Config) error {
+	file, err := registry.Load(cfg.Registry.Path)
+	if err != nil {
+		return err
+	}
+
+	changes, err := registry.New(log, storage, cfg.Signing.SecretGracePeriod).
+		Reconcile(ctx, file, registry.Options{Prune: cfg.Registry.Prune})
+	if err != nil {
+		return err
+	}
+
+	log.Info("apps reconciled", slog.String("path", cfg.Registry.Path), slog.Int("changes", len(changes)))
+
+	return nil
+}
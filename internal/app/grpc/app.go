@@ -5,7 +5,9 @@ import (
 	"google.golang.org/grpc"
 	"log/slog"
 	"net"
+	admingrpc "sso/internal/grpc/admin"
 	authgrpc "sso/internal/grpc/auth"
+	"sso/internal/grpc/authz"
 )
 
 type App struct {
@@ -14,11 +16,27 @@ type App struct {
 	port       int
 }
 
-func New(log *slog.Logger, authService authgrpc.Auth, port int) *App {
-
-	gRPCServer := grpc.NewServer()
+func New(
+	log *slog.Logger,
+	authService authgrpc.Auth,
+	keyService authgrpc.Keys,
+	rbacService authgrpc.RBAC,
+	adminService admingrpc.Admin,
+	authenticator authz.Authenticator,
+	port int,
+) *App {
+
+	gRPCServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			authz.UnaryServerInterceptor(log, policy, authenticator),
+		),
+		grpc.ChainStreamInterceptor(
+			authz.StreamServerInterceptor(log, policy, authenticator),
+		),
+	)
 
-	authgrpc.Register(gRPCServer, authService)
+	authgrpc.Register(gRPCServer, authService, keyService, rbacService)
+	admingrpc.Register(gRPCServer, adminService)
 
 	return &App{
 		log:        log,
@@ -0,0 +1,51 @@
+package grpcapp
+
+import (
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"sso/internal/grpc/authz"
+)
+
+// policy declares who may call each RPC. RPCs that are not listed are
+// rejected, so every new RPC has to be added here.
+var policy = authz.Policy{
+	ssov1.Auth_Register_FullMethodName:                  authz.Public,
+	ssov1.Auth_VerifyEmail_FullMethodName:               authz.Public,
+	ssov1.Auth_SendVerificationEmail_FullMethodName:     authz.Public,
+	ssov1.Auth_RequestPasswordReset_FullMethodName:      authz.Public,
+	ssov1.Auth_ResetPassword_FullMethodName:             authz.Public,
+	ssov1.Auth_ChangePassword_FullMethodName:            authz.Public,
+	ssov1.Auth_Login_FullMethodName:                     authz.Public,
+	ssov1.Auth_Refresh_FullMethodName:                   authz.Public,
+	ssov1.Auth_ExchangeSession_FullMethodName:           authz.Public,
+	ssov1.Auth_Logout_FullMethodName:                    authz.Public,
+	ssov1.Auth_EnrollTOTP_FullMethodName:                authz.Public,
+	ssov1.Auth_ConfirmTOTP_FullMethodName:               authz.Public,
+	ssov1.Auth_VerifyMFA_FullMethodName:                 authz.Public,
+	ssov1.Auth_BeginPasskeyRegistration_FullMethodName:  authz.Public,
+	ssov1.Auth_FinishPasskeyRegistration_FullMethodName: authz.Public,
+	ssov1.Auth_BeginPasskeyLogin_FullMethodName:         authz.Public,
+	ssov1.Auth_FinishPasskeyLogin_FullMethodName:        authz.Public,
+
+	ssov1.Auth_IsAdmin_FullMethodName:        authz.App,
+	ssov1.Auth_IsTokenRevoked_FullMethodName: authz.App,
+	ssov1.Auth_Introspect_FullMethodName:     authz.App,
+	ssov1.Auth_HasPermission_FullMethodName:  authz.App,
+
+	ssov1.Auth_RevokeToken_FullMethodName:      authz.Admin,
+	ssov1.Auth_RotateSigningKey_FullMethodName: authz.Admin,
+	ssov1.Auth_AssignRole_FullMethodName:       authz.Admin,
+	ssov1.Auth_RevokeRole_FullMethodName:       authz.Admin,
+	ssov1.Auth_GrantPermission_FullMethodName:  authz.Admin,
+
+	ssov1.Admin_CreateApp_FullMethodName:        authz.Admin,
+	ssov1.Admin_ListApps_FullMethodName:         authz.Admin,
+	ssov1.Admin_UpdateApp_FullMethodName:        authz.Admin,
+	ssov1.Admin_DeleteApp_FullMethodName:        authz.Admin,
+	ssov1.Admin_RotateAppSecret_FullMethodName:  authz.Admin,
+	ssov1.Admin_RetireAppSecrets_FullMethodName: authz.Admin,
+	ssov1.Admin_ListUsers_FullMethodName:        authz.Admin,
+	ssov1.Admin_GetUser_FullMethodName:          authz.Admin,
+	ssov1.Admin_SetAdmin_FullMethodName:         authz.Admin,
+	ssov1.Admin_DisableUser_FullMethodName:      authz.Admin,
+	ssov1.Admin_DeleteUser_FullMethodName:       authz.Admin,
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sso/internal/config"
+	"sso/internal/lib/envelope"
+	"sso/internal/services/admin"
+	"sso/internal/services/auth"
+	"sso/internal/services/keys"
+	"sso/internal/services/rbac"
+	"sso/internal/services/registry"
+	"sso/internal/storage/memory"
+	"sso/internal/storage/postgres"
+	"sso/internal/storage/schema"
+	"sso/internal/storage/sqlite"
+)
+
+// Storage is implemented by every storage backend.
+type Storage interface {
+	auth.UserSaver
+	auth.UserProvider
+	auth.AppProvider
+	auth.RefreshTokenProvider
+	auth.RevokedTokenProvider
+	auth.SessionProvider
+	auth.RoleProvider
+	auth.MFAProvider
+	auth.PasskeyProvider
+	auth.EmailVerificationProvider
+	auth.PasswordResetProvider
+	auth.PasswordProvider
+	keys.KeyStorage
+	rbac.RoleProvider
+	admin.AppManager
+	admin.UserManager
+	registry.AppStorage
+	io.Closer
+}
+
+// NewStorage opens the storage backend selected by cfg.
+func NewStorage(cfg config.StorageConfig, encryption config.EncryptionConfig) (Storage, error) {
+	switch cfg.Driver {
+	case config.StorageDriverMemory:
+		storage := memory.New()
+		if cfg.Seed != "" {
+			if err := storage.LoadSeed(cfg.Seed); err != nil {
+				return nil, err
+			}
+		}
+
+		return storage, nil
+	case config.StorageDriverSQLite:
+		keyring, err := envelope.LoadKeyring(encryption.MasterKey, encryption.MasterKeyFile, encryption.PreviousMasterKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		return sqlite.New(cfg.Path, keyring)
+	case config.StorageDriverPostgres:
+		keyring, err := envelope.LoadKeyring(encryption.MasterKey, encryption.MasterKeyFile, encryption.PreviousMasterKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		dsn, err := cfg.URL()
+		if err != nil {
+			return nil, err
+		}
+
+		return postgres.New(dsn, postgres.PoolConfig{
+			MaxOpenConns:    cfg.MaxOpenConns,
+			MaxIdleConns:    cfg.MaxIdleConns,
+			ConnMaxLifetime: cfg.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		}, keyring)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// migrateSchema applies the migrations embedded in the binary to the
+// configured database. The memory driver has no schema.
+func migrateSchema(ctx context.Context, log *slog.Logger, cfg config.StorageConfig) error {
+	var version uint
+
+	switch cfg.Driver {
+	case config.StorageDriverPostgres:
+		dsn, err := cfg.URL()
+		if err != nil {
+			return err
+		}
+
+		if version, err = schema.UpPostgres(ctx, dsn); err != nil {
+			return err
+		}
+	case config.StorageDriverSQLite:
+		var err error
+		if version, err = schema.UpSQLite(cfg.Path); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	log.Info("schema is up to date", slog.Uint64("version", uint64(version)))
+
+	return nil
+}
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,9 +18,21 @@ const (
 )
 
 type Config struct {
-	Env      string        `yaml:"env" env-default:"local"`
-	TokenTTL time.Duration `yaml:"token_ttl" env-required:"true"`
-	GRPC     GRPCConfig    `yaml:"grpc"`
+	Env             string           `yaml:"env" env-default:"local"`
+	TokenTTL        time.Duration    `yaml:"token_ttl" env-required:"true"`
+	RefreshTokenTTL time.Duration    `yaml:"refresh_token_ttl" env-default:"720h"`
+	Tokens          TokensConfig     `yaml:"tokens"`
+	GRPC            GRPCConfig       `yaml:"grpc"`
+	HTTP            HTTPConfig       `yaml:"http"`
+	Signing         SigningConfig    `yaml:"signing"`
+	Session         SessionConfig    `yaml:"session"`
+	Password        PasswordConfig   `yaml:"password"`
+	MFA             MFAConfig        `yaml:"mfa"`
+	WebAuthn        WebAuthnConfig   `yaml:"webauthn"`
+	Mail            MailConfig       `yaml:"mail"`
+	Encryption      EncryptionConfig `yaml:"encryption"`
+	Storage         StorageConfig    `yaml:"storage"`
+	Registry        RegistryConfig   `yaml:"registry"`
 }
 
 type GRPCConfig struct {
@@ -22,6 +40,212 @@ type GRPCConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+type HTTPConfig struct {
+	Port    int           `yaml:"port"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+// TokensConfig sets the claims of access tokens. Issuer is the iss claim of
+// tokens for apps that do not set their own issuer. ClaimsVersion 1 keeps the
+// legacy uid and app_id claims next to the registered ones; version 2 drops
+// them once every verifier reads sub and azp instead.
+type TokensConfig struct {
+	Issuer        string `yaml:"issuer" env:"SSO_TOKENS_ISSUER" env-default:"sso"`
+	ClaimsVersion int    `yaml:"claims_version" env-default:"1"`
+}
+
+type SigningConfig struct {
+	// Algorithm is HS256 (per-app secret) or one of RS256, ES256, EdDSA
+	// (key pair managed by the service and published as JWKS).
+	Algorithm string `yaml:"algorithm" env-default:"HS256"`
+	// RotationInterval is how long a managed key signs tokens before the
+	// next one takes over. RotationCheckInterval is how often the rotator
+	// looks at the keys.
+	RotationInterval      time.Duration `yaml:"rotation_interval" env-default:"720h"`
+	RotationCheckInterval time.Duration `yaml:"rotation_check_interval" env-default:"1m"`
+	// SecretGracePeriod is how long an app secret is still accepted after
+	// it was rotated, unless the rotation asks for another period. It
+	// should be at least TokenTTL so HS256 tokens outlive their secret.
+	SecretGracePeriod time.Duration `yaml:"secret_grace_period" env-default:"24h"`
+}
+
+// SessionConfig sets the lifetime of the SSO session returned by Login. A
+// session ends at TTL after login or after IdleTimeout without being used,
+// whichever comes first.
+type SessionConfig struct {
+	TTL         time.Duration `yaml:"ttl" env-default:"720h"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"72h"`
+}
+
+// PasswordConfig sets how passwords are hashed and changed. HashCost is the
+// bcrypt cost of new password hashes. A password can be changed without
+// giving the current one within StepUpMaxAge of logging in.
+type PasswordConfig struct {
+	HashCost     int           `yaml:"hash_cost" env-default:"10"`
+	StepUpMaxAge time.Duration `yaml:"step_up_max_age" env-default:"5m"`
+}
+
+// MFAConfig sets up the second factor. Issuer names the service in
+// authenticator apps. After the password check a login waits ChallengeTTL for
+// the second factor and fails after MaxAttempts wrong codes. After
+// MaxFailures wrong codes in a row, across logins, the second factor of the
+// user is refused for Lockout.
+type MFAConfig struct {
+	Issuer       string        `yaml:"issuer" env-default:"SSO"`
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"5"`
+	MaxFailures  int           `yaml:"max_failures" env-default:"10"`
+	Lockout      time.Duration `yaml:"lockout" env-default:"15m"`
+}
+
+// WebAuthnConfig sets up passkeys. RPID is the domain passkeys are bound to
+// and RPOrigins the origins of the pages that may use them. Passkeys are
+// disabled if RPID is empty. A registration or login waits CeremonyTTL for
+// the authenticator.
+type WebAuthnConfig struct {
+	RPID          string        `yaml:"rp_id" env:"SSO_WEBAUTHN_RP_ID"`
+	RPDisplayName string        `yaml:"rp_display_name" env-default:"SSO"`
+	RPOrigins     []string      `yaml:"rp_origins"`
+	CeremonyTTL   time.Duration `yaml:"ceremony_ttl" env-default:"5m"`
+}
+
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverLog  = "log"
+	MailDriverFile = "file"
+)
+
+// MailConfig selects how emails are sent. Driver is smtp, log or file.
+//
+// The smtp driver sends them through the server in SMTP, with STARTTLS if the
+// server offers it. The password may also be given in the
+// SSO_MAIL_SMTP_PASSWORD environment variable.
+//
+// The log driver only logs them and the file driver writes each to its own
+// file in Dir; both are meant for tests and local development.
+//
+// Email verification tokens are valid for VerificationTTL and password reset
+// tokens for PasswordResetTTL. If VerificationURL or PasswordResetURL is set
+// the email links to it with the token in the token query parameter.
+type MailConfig struct {
+	Driver string     `yaml:"driver" env-default:"log"`
+	From   string     `yaml:"from" env-default:"sso@localhost"`
+	Dir    string     `yaml:"dir"`
+	SMTP   SMTPConfig `yaml:"smtp"`
+
+	VerificationTTL time.Duration `yaml:"verification_ttl" env-default:"24h"`
+	VerificationURL string        `yaml:"verification_url"`
+
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl" env-default:"30m"`
+	PasswordResetURL string        `yaml:"password_reset_url"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" env-default:"localhost"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" env:"SSO_MAIL_SMTP_PASSWORD"`
+}
+
+// EncryptionConfig holds the master key that wraps the data keys app secrets
+// and signing keys are encrypted with. Exactly one of MasterKey (base64) and
+// MasterKeyFile must be set. PreviousMasterKeys (base64) are only used to
+// read data keys until the rekey command has re-wrapped them with the
+// current master key.
+type EncryptionConfig struct {
+	MasterKey          string   `yaml:"master_key" env:"SSO_MASTER_KEY"`
+	MasterKeyFile      string   `yaml:"master_key_file" env:"SSO_MASTER_KEY_FILE"`
+	PreviousMasterKeys []string `yaml:"previous_master_keys"`
+}
+
+// RegistryConfig names the file apps are declared in. If Path is set the
+// server reconciles the stored apps with it on startup; apps the file does
+// not list are only deleted with Prune.
+type RegistryConfig struct {
+	Path  string `yaml:"path" env:"SSO_REGISTRY_PATH"`
+	Prune bool   `yaml:"prune"`
+}
+
+const (
+	StorageDriverPostgres = "postgres"
+	StorageDriverMemory   = "memory"
+	StorageDriverSQLite   = "sqlite"
+)
+
+// StorageConfig selects the storage backend. Driver is postgres, sqlite or
+// memory.
+//
+// The memory driver keeps everything in process memory and is meant for
+// tests and local development; Seed optionally names a YAML file with users
+// to register at startup. Its apps come from the registry file, as with the
+// other drivers.
+//
+// The sqlite driver keeps everything in the database file at Path and suits
+// single-node deployments.
+//
+// With AutoMigrate the server applies the migrations embedded in the binary
+// before it opens a postgres or sqlite database.
+//
+// For postgres either DSN, a postgres:// URL, is set or the connection is
+// built from the other fields. The password may be given inline, in
+// PasswordFile or in the SSO_STORAGE_PASSWORD environment variable.
+type StorageConfig struct {
+	Driver string `yaml:"driver" env-default:"postgres"`
+	Seed   string `yaml:"seed"`
+	Path   string `yaml:"path" env-default:"sso.db"`
+
+	AutoMigrate bool `yaml:"auto_migrate" env:"SSO_STORAGE_AUTO_MIGRATE"`
+
+	DSN          string `yaml:"dsn" env:"SSO_STORAGE_DSN"`
+	Host         string `yaml:"host" env-default:"localhost"`
+	Port         int    `yaml:"port" env-default:"5432"`
+	User         string `yaml:"user" env-default:"postgres"`
+	Password     string `yaml:"password" env:"SSO_STORAGE_PASSWORD"`
+	PasswordFile string `yaml:"password_file" env:"SSO_STORAGE_PASSWORD_FILE"`
+	DBName       string `yaml:"dbname" env-default:"sso"`
+	SSLMode      string `yaml:"sslmode" env-default:"require"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" env-default:"25"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env-default:"25"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env-default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env-default:"5m"`
+}
+
+// URL returns the connection URL, reading the password file if one is
+// configured.
+func (c StorageConfig) URL() (string, error) {
+	if c.DSN != "" {
+		return c.DSN, nil
+	}
+
+	password := c.Password
+	if c.PasswordFile != "" {
+		if password != "" {
+			return "", errors.New("storage password and password file are mutually exclusive")
+		}
+
+		raw, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("read storage password file: %w", err)
+		}
+		password = strings.TrimRight(string(raw), "\r\n")
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.DBName,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	if password != "" {
+		u.User = url.UserPassword(c.User, password)
+	} else {
+		u.User = url.User(c.User)
+	}
+
+	return u.String(), nil
+}
+
 func MustLoad() *Config {
 	path := fetchConfigPath()
 
@@ -29,17 +253,34 @@ func MustLoad() *Config {
 		panic("config path is empty")
 	}
 
+	return MustLoadPath(path)
+}
+
+// MustLoadPath loads the config file at path without looking at the
+// command line.
+func MustLoadPath(path string) *Config {
+	config, err := LoadPath(path)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return config
+}
+
+// LoadPath is MustLoadPath for callers that report a bad config file
+// themselves.
+func LoadPath(path string) (*Config, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		panic("config path does not exist" + path)
+		return nil, fmt.Errorf("config path does not exist: %s", path)
 	}
 
 	var config Config
 
 	if err := cleanenv.ReadConfig(path, &config); err != nil {
-		panic("failed to read config: " + err.Error())
+		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
-	return &config
+	return &config, nil
 }
 
 // Path to cfg: flag >  default
@@ -1,7 +1,61 @@
 package models
 
+import "time"
+
+// App secret states. New tokens are signed with the newest active secret;
+// verify-only secrets are still accepted until they expire.
+const (
+	AppSecretActive     = "active"
+	AppSecretVerifyOnly = "verify_only"
+)
+
 type App struct {
 	ID     int
 	Name   string
 	Secret string
+	// Secrets holds every secret of the app that has not expired, newest
+	// first. It is only loaded when a single app is looked up.
+	Secrets []AppSecret
+	// TokenTTL is the lifetime of access tokens issued for the app. Zero
+	// means the service default.
+	TokenTTL time.Duration
+	// Scopes lists the scopes granted to the app. Its access tokens carry
+	// them in the scope claim.
+	Scopes []string
+	// Issuer and Audience override the service issuer and the app ID in the
+	// iss and aud claims of the app's access tokens when set.
+	Issuer   string
+	Audience string
+	// Claims lists the optional claims the app's access tokens carry. Nil
+	// means DefaultClaims; an empty list means none.
+	Claims []string
+	// RequireVerifiedEmail refuses logins to the app of users who have not
+	// verified their email address.
+	RequireVerifiedEmail bool
+}
+
+// Optional claims an app can ask for.
+const (
+	ClaimEmail   = "email"
+	ClaimIsAdmin = "is_admin"
+	ClaimRoles   = "roles"
+)
+
+// DefaultClaims are the optional claims of apps that do not list their own.
+var DefaultClaims = []string{ClaimEmail, ClaimRoles}
+
+// TokenClaims returns the optional claims the app's access tokens carry.
+func (a App) TokenClaims() []string {
+	if a.Claims == nil {
+		return DefaultClaims
+	}
+
+	return a.Claims
+}
+
+type AppSecret struct {
+	Secret    string
+	State     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Audit event actions.
+const (
+	AuditPasswordChanged = "password_changed"
+	AuditPasswordReset   = "password_reset"
+)
+
+// Audit event methods: how the user proved it was them.
+const (
+	AuditMethodCurrentPassword = "current_password"
+	AuditMethodRecentLogin     = "recent_login"
+	AuditMethodResetToken      = "reset_token"
+)
+
+// AuditEvent records a security-relevant change to the account of a user.
+type AuditEvent struct {
+	ID        int64
+	UserID    int64
+	Action    string
+	Method    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Introspection describes a token as seen by the service. Only Active is set
+// for tokens that are invalid, expired or revoked.
+type Introspection struct {
+	Active    bool
+	TokenID   string
+	UID       int64
+	Email     string
+	AppID     int
+	ExpiresAt time.Time
+	Scopes    []string
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// TOTP is the authenticator a user enrolled as second factor. It guards
+// logins only once ConfirmedAt is set. LastStep is the time step of the last
+// accepted code; codes of that step or earlier are rejected.
+type TOTP struct {
+	UserID      int64
+	Secret      []byte
+	CreatedAt   time.Time
+	ConfirmedAt time.Time
+	LastStep    int64
+	// Failures counts the wrong second factors in a row, across logins;
+	// LastFailureAt is the time of the last of them.
+	Failures      int
+	LastFailureAt time.Time
+}
+
+// TOTPEnrollment is what a user needs to add a new authenticator to their
+// app: the secret in base32 and the otpauth:// URI to render as QR code.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAChallenge is a login that passed the password check and waits for the
+// second factor. Attempts counts the wrong codes presented so far.
+type MFAChallenge struct {
+	TokenHash []byte
+	UserID    int64
+	AppID     int64
+	ExpiresAt time.Time
+	Attempts  int
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Passkey is a WebAuthn credential a user registered to sign in without a
+// password. PublicKey is COSE encoded. SignCount is the signature counter
+// the authenticator reported last; authenticators without a counter always
+// report zero.
+type Passkey struct {
+	ID              int64
+	UserID          int64
+	CredentialID    []byte
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      []string
+	BackupEligible  bool
+	CreatedAt       time.Time
+	LastUsedAt      time.Time
+}
+
+// WebAuthnCeremony is a passkey registration or login that was begun and
+// waits for the response of the authenticator. Session is the encoded state
+// the response is verified against. AppID is only set for logins.
+type WebAuthnCeremony struct {
+	TokenHash []byte
+	UserID    int64
+	AppID     int64
+	Session   []byte
+	ExpiresAt time.Time
+}
@@ -0,0 +1,11 @@
+package models
+
+// PasswordChange sets the password hash of UserID to PassHash. The SSO
+// sessions of the user other than KeepSessionID, which is zero to keep none,
+// end with it and Event records it.
+type PasswordChange struct {
+	UserID        int64
+	PassHash      []byte
+	KeepSessionID int64
+	Event         AuditEvent
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PasswordReset is a pending password reset of a user. The token is sent to
+// the email of the user and only its hash is stored.
+type PasswordReset struct {
+	TokenHash []byte
+	UserID    int64
+	ExpiresAt time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID        int64
+	TokenHash []byte
+	FamilyID  string
+	UserID    int64
+	AppID     int
+	ExpiresAt time.Time
+	Rotated   bool
+	Revoked   bool
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session is the SSO session a user gets on login. It is not bound to an app
+// and can be exchanged for tokens of any registered app.
+type Session struct {
+	ID         int64
+	TokenHash  []byte
+	UserID     int64
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	Revoked    bool
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Signing key states. A key is published as pending before it signs anything,
+// signs new tokens while active, is still published while retiring so that
+// tokens it signed can be verified, and is dropped once retired.
+const (
+	KeyStatePending  = "pending"
+	KeyStateActive   = "active"
+	KeyStateRetiring = "retiring"
+	KeyStateRetired  = "retired"
+)
+
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	State       string
+	PrivateKey  []byte
+	PublicKey   []byte
+	CreatedAt   time.Time
+	ActivatedAt time.Time
+	RetireAt    time.Time
+}
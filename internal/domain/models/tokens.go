@@ -0,0 +1,10 @@
+package models
+
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	SessionToken string
+	// MFAToken is set instead of the other tokens if the login has to be
+	// completed with a second factor.
+	MFAToken string
+}
@@ -1,7 +1,10 @@
 package models
 
 type User struct {
-	ID       int64
-	Email    string
-	PassHash []byte
+	ID            int64
+	Email         string
+	PassHash      []byte
+	IsAdmin       bool
+	Disabled      bool
+	EmailVerified bool
 }
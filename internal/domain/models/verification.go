@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// EmailVerification is a pending verification of the address Email of a
+// user. The token is sent to the address and only its hash is stored.
+type EmailVerification struct {
+	TokenHash []byte
+	UserID    int64
+	Email     string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,271 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/domain/models"
+	"sso/internal/services/admin"
+	"time"
+)
+
+const (
+	emptyValue = 0
+)
+
+type Admin interface {
+	CreateApp(ctx context.Context, app models.App) (models.App, error)
+	ListApps(ctx context.Context) ([]models.App, error)
+	UpdateApp(ctx context.Context, app models.App) error
+	DeleteApp(ctx context.Context, appID int64) error
+	RotateAppSecret(ctx context.Context, appID int64, gracePeriod time.Duration) (string, time.Time, error)
+	RetireAppSecrets(ctx context.Context, appID int64) (int64, error)
+	ListUsers(ctx context.Context, afterID int64, pageSize int) ([]models.User, error)
+	GetUser(ctx context.Context, userID int64) (models.User, error)
+	SetAdmin(ctx context.Context, userID int64, isAdmin bool) error
+	DisableUser(ctx context.Context, userID int64, disabled bool) error
+	DeleteUser(ctx context.Context, userID int64) error
+}
+
+type serverAPI struct {
+	ssov1.UnimplementedAdminServer
+	admin Admin
+}
+
+// Register registers the Admin service. Every RPC of the service must be
+// declared admin-only in the authorization policy of the gRPC server.
+func Register(gRPC *grpc.Server, admin Admin) {
+	ssov1.RegisterAdminServer(gRPC, &serverAPI{admin: admin})
+}
+
+func (s *serverAPI) CreateApp(ctx context.Context, req *ssov1.CreateAppRequest) (*ssov1.CreateAppResponse, error) {
+	if err := validateCreateApp(req); err != nil {
+		return nil, err
+	}
+
+	app, err := s.admin.CreateApp(ctx, models.App{
+		ID:     int(req.GetAppId()),
+		Name:   req.GetName(),
+		Secret: req.GetSecret(),
+	})
+	if err != nil {
+		if errors.Is(err, admin.ErrAppExists) {
+			return nil, status.Error(codes.AlreadyExists, "app already exists")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.CreateAppResponse{
+		App:    toApp(app),
+		Secret: app.Secret,
+	}, nil
+}
+
+func (s *serverAPI) ListApps(ctx context.Context, req *ssov1.ListAppsRequest) (*ssov1.ListAppsResponse, error) {
+	apps, err := s.admin.ListApps(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	res := &ssov1.ListAppsResponse{Apps: make([]*ssov1.App, 0, len(apps))}
+	for _, app := range apps {
+		res.Apps = append(res.Apps, toApp(app))
+	}
+
+	return res, nil
+}
+
+func (s *serverAPI) UpdateApp(ctx context.Context, req *ssov1.UpdateAppRequest) (*ssov1.UpdateAppResponse, error) {
+	if err := validateUpdateApp(req); err != nil {
+		return nil, err
+	}
+
+	err := s.admin.UpdateApp(ctx, models.App{
+		ID:   int(req.GetAppId()),
+		Name: req.GetName(),
+	})
+	if err != nil {
+		if errors.Is(err, admin.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.UpdateAppResponse{}, nil
+}
+
+func (s *serverAPI) DeleteApp(ctx context.Context, req *ssov1.DeleteAppRequest) (*ssov1.DeleteAppResponse, error) {
+	if req.GetAppId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "app required")
+	}
+
+	if err := s.admin.DeleteApp(ctx, int64(req.GetAppId())); err != nil {
+		if errors.Is(err, admin.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.DeleteAppResponse{}, nil
+}
+
+func (s *serverAPI) RotateAppSecret(ctx context.Context, req *ssov1.RotateAppSecretRequest) (*ssov1.RotateAppSecretResponse, error) {
+	if req.GetAppId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "app required")
+	}
+
+	if req.GetGracePeriodSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "grace period must not be negative")
+	}
+
+	gracePeriod := time.Duration(req.GetGracePeriodSeconds()) * time.Second
+
+	secret, retireAt, err := s.admin.RotateAppSecret(ctx, int64(req.GetAppId()), gracePeriod)
+	if err != nil {
+		if errors.Is(err, admin.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.RotateAppSecretResponse{
+		Secret:   secret,
+		RetireAt: retireAt.Unix(),
+	}, nil
+}
+
+func (s *serverAPI) RetireAppSecrets(ctx context.Context, req *ssov1.RetireAppSecretsRequest) (*ssov1.RetireAppSecretsResponse, error) {
+	if req.GetAppId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "app required")
+	}
+
+	retired, err := s.admin.RetireAppSecrets(ctx, int64(req.GetAppId()))
+	if err != nil {
+		if errors.Is(err, admin.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.RetireAppSecretsResponse{Retired: retired}, nil
+}
+
+func (s *serverAPI) ListUsers(ctx context.Context, req *ssov1.ListUsersRequest) (*ssov1.ListUsersResponse, error) {
+	users, err := s.admin.ListUsers(ctx, req.GetAfterId(), int(req.GetPageSize()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	res := &ssov1.ListUsersResponse{Users: make([]*ssov1.User, 0, len(users))}
+	for _, user := range users {
+		res.Users = append(res.Users, toUser(user))
+	}
+
+	return res, nil
+}
+
+func (s *serverAPI) GetUser(ctx context.Context, req *ssov1.GetUserRequest) (*ssov1.GetUserResponse, error) {
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	user, err := s.admin.GetUser(ctx, req.GetUserId())
+	if err != nil {
+		if errors.Is(err, admin.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.GetUserResponse{
+		User: toUser(user),
+	}, nil
+}
+
+func (s *serverAPI) SetAdmin(ctx context.Context, req *ssov1.SetAdminRequest) (*ssov1.SetAdminResponse, error) {
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	if err := s.admin.SetAdmin(ctx, req.GetUserId(), req.GetIsAdmin()); err != nil {
+		if errors.Is(err, admin.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.SetAdminResponse{}, nil
+}
+
+func (s *serverAPI) DisableUser(ctx context.Context, req *ssov1.DisableUserRequest) (*ssov1.DisableUserResponse, error) {
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	if err := s.admin.DisableUser(ctx, req.GetUserId(), req.GetDisabled()); err != nil {
+		if errors.Is(err, admin.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.DisableUserResponse{}, nil
+}
+
+func (s *serverAPI) DeleteUser(ctx context.Context, req *ssov1.DeleteUserRequest) (*ssov1.DeleteUserResponse, error) {
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	if err := s.admin.DeleteUser(ctx, req.GetUserId()); err != nil {
+		if errors.Is(err, admin.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.DeleteUserResponse{}, nil
+}
+
+func toApp(app models.App) *ssov1.App {
+	return &ssov1.App{
+		Id:   int32(app.ID),
+		Name: app.Name,
+	}
+}
+
+func toUser(user models.User) *ssov1.User {
+	return &ssov1.User{
+		Id:       user.ID,
+		Email:    user.Email,
+		IsAdmin:  user.IsAdmin,
+		Disabled: user.Disabled,
+	}
+}
+
+func validateCreateApp(req *ssov1.CreateAppRequest) error {
+	if req.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app required")
+	}
+
+	if req.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "name required")
+	}
+
+	return nil
+}
+
+func validateUpdateApp(req *ssov1.UpdateAppRequest) error {
+	if req.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app required")
+	}
+
+	if req.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "name required")
+	}
+
+	return nil
+}
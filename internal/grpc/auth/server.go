@@ -7,7 +7,10 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"sso/internal/domain/models"
 	"sso/internal/services/auth"
+	"sso/internal/services/keys"
+	"sso/internal/services/rbac"
 )
 
 const (
@@ -15,18 +18,49 @@ const (
 )
 
 type Auth interface {
-	Login(ctx context.Context, email string, password string, appID int64) (string, error)
+	Login(ctx context.Context, email string, password string, appID int64) (models.Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (models.Tokens, error)
+	ExchangeSession(ctx context.Context, sessionToken string, appID int64) (models.Tokens, error)
 	RegisterNewUser(ctx context.Context, email string, password string) (userID int64, err error)
+	VerifyEmail(ctx context.Context, token string) error
+	SendVerificationEmail(ctx context.Context, email string)
+	RequestPasswordReset(ctx context.Context, email string)
+	ResetPassword(ctx context.Context, token string, password string) error
+	ChangePassword(ctx context.Context, accessToken string, sessionToken string, currentPassword string, newPassword string, keepSession bool) error
 	IsAdmin(ctx context.Context, userID int64) (isAdmin bool, err error)
+	Logout(ctx context.Context, accessToken string, refreshToken string, sessionToken string) error
+	RevokeToken(ctx context.Context, token string) error
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	Introspect(ctx context.Context, token string) (models.Introspection, error)
+	EnrollTOTP(ctx context.Context, accessToken string) (models.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, accessToken string, code string) (recoveryCodes []string, err error)
+	VerifyMFA(ctx context.Context, mfaToken string, code string) (models.Tokens, error)
+	BeginPasskeyRegistration(ctx context.Context, accessToken string) (options []byte, ceremonyToken string, err error)
+	FinishPasskeyRegistration(ctx context.Context, accessToken string, ceremonyToken string, response []byte) error
+	BeginPasskeyLogin(ctx context.Context, email string, appID int64) (options []byte, ceremonyToken string, err error)
+	FinishPasskeyLogin(ctx context.Context, ceremonyToken string, response []byte) (models.Tokens, error)
+}
+
+type Keys interface {
+	Rotate(ctx context.Context, force bool) error
+}
+
+type RBAC interface {
+	AssignRole(ctx context.Context, userID int64, appID int64, role string) error
+	RevokeRole(ctx context.Context, userID int64, appID int64, role string) error
+	GrantPermission(ctx context.Context, appID int64, role string, permission string) error
+	HasPermission(ctx context.Context, userID int64, appID int64, permission string) (bool, error)
 }
 
 type serverAPI struct {
 	ssov1.UnimplementedAuthServer
 	auth Auth
+	keys Keys
+	rbac RBAC
 }
 
-func Register(gRPC *grpc.Server, auth Auth) {
-	ssov1.RegisterAuthServer(gRPC, &serverAPI{auth: auth})
+func Register(gRPC *grpc.Server, auth Auth, keys Keys, rbac RBAC) {
+	ssov1.RegisterAuthServer(gRPC, &serverAPI{auth: auth, keys: keys, rbac: rbac})
 }
 
 func (s *serverAPI) Login(ctx context.Context, req *ssov1.LoginRequest) (*ssov1.LoginResponse, error) {
@@ -34,16 +68,275 @@ func (s *serverAPI) Login(ctx context.Context, req *ssov1.LoginRequest) (*ssov1.
 		return nil, err
 	}
 
-	token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), int64(req.GetAppId()))
+	tokens, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), int64(req.GetAppId()))
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "invalid login or password")
 		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user disabled")
+		}
+		if errors.Is(err, auth.ErrEmailNotVerified) {
+			return nil, status.Error(codes.FailedPrecondition, "email not verified")
+		}
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
+	if tokens.MFAToken != "" {
+		return &ssov1.LoginResponse{
+			MfaRequired: true,
+			MfaToken:    tokens.MFAToken,
+		}, nil
+	}
+
 	return &ssov1.LoginResponse{
-		Token: token,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		SessionToken: tokens.SessionToken,
+	}, nil
+}
+
+func (s *serverAPI) ExchangeSession(ctx context.Context, req *ssov1.ExchangeSessionRequest) (*ssov1.ExchangeSessionResponse, error) {
+	if err := validateExchangeSession(req); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.ExchangeSession(ctx, req.GetSessionToken(), int64(req.GetAppId()))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidSession) {
+			return nil, status.Error(codes.Unauthenticated, "invalid session")
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user disabled")
+		}
+		if errors.Is(err, auth.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		if errors.Is(err, auth.ErrEmailNotVerified) {
+			return nil, status.Error(codes.FailedPrecondition, "email not verified")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.ExchangeSessionResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *serverAPI) EnrollTOTP(ctx context.Context, req *ssov1.EnrollTOTPRequest) (*ssov1.EnrollTOTPResponse, error) {
+	if err := validateEnrollTOTP(req); err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.auth.EnrollTOTP(ctx, req.GetToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if errors.Is(err, auth.ErrMFAAlreadyEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, "mfa already enabled")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.EnrollTOTPResponse{
+		Secret: enrollment.Secret,
+		Uri:    enrollment.URI,
+	}, nil
+}
+
+func (s *serverAPI) ConfirmTOTP(ctx context.Context, req *ssov1.ConfirmTOTPRequest) (*ssov1.ConfirmTOTPResponse, error) {
+	if err := validateConfirmTOTP(req); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.auth.ConfirmTOTP(ctx, req.GetToken(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			return nil, status.Error(codes.InvalidArgument, "invalid code")
+		}
+		if errors.Is(err, auth.ErrMFANotEnrolled) {
+			return nil, status.Error(codes.FailedPrecondition, "mfa not enrolled")
+		}
+		if errors.Is(err, auth.ErrMFAAlreadyEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, "mfa already enabled")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.ConfirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *serverAPI) VerifyMFA(ctx context.Context, req *ssov1.VerifyMFARequest) (*ssov1.VerifyMFAResponse, error) {
+	if err := validateVerifyMFA(req); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.VerifyMFA(ctx, req.GetMfaToken(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidMFAToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid mfa token")
+		}
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			return nil, status.Error(codes.InvalidArgument, "invalid code")
+		}
+		if errors.Is(err, auth.ErrMFALocked) {
+			return nil, status.Error(codes.ResourceExhausted, "too many wrong codes, try again later")
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user disabled")
+		}
+		if errors.Is(err, auth.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.VerifyMFAResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		SessionToken: tokens.SessionToken,
+	}, nil
+}
+
+func (s *serverAPI) BeginPasskeyRegistration(ctx context.Context, req *ssov1.BeginPasskeyRegistrationRequest) (*ssov1.BeginPasskeyRegistrationResponse, error) {
+	if err := validateBeginPasskeyRegistration(req); err != nil {
+		return nil, err
+	}
+
+	options, ceremonyToken, err := s.auth.BeginPasskeyRegistration(ctx, req.GetToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if errors.Is(err, auth.ErrPasskeysDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, "passkeys disabled")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.BeginPasskeyRegistrationResponse{
+		Options:       string(options),
+		CeremonyToken: ceremonyToken,
+	}, nil
+}
+
+func (s *serverAPI) FinishPasskeyRegistration(ctx context.Context, req *ssov1.FinishPasskeyRegistrationRequest) (*ssov1.FinishPasskeyRegistrationResponse, error) {
+	if err := validateFinishPasskeyRegistration(req); err != nil {
+		return nil, err
+	}
+
+	err := s.auth.FinishPasskeyRegistration(ctx, req.GetToken(), req.GetCeremonyToken(), []byte(req.GetCredential()))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if errors.Is(err, auth.ErrInvalidCeremony) {
+			return nil, status.Error(codes.Unauthenticated, "invalid ceremony token")
+		}
+		if errors.Is(err, auth.ErrInvalidPasskey) {
+			return nil, status.Error(codes.InvalidArgument, "invalid credential")
+		}
+		if errors.Is(err, auth.ErrPasskeyExists) {
+			return nil, status.Error(codes.AlreadyExists, "passkey already registered")
+		}
+		if errors.Is(err, auth.ErrPasskeysDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, "passkeys disabled")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.FinishPasskeyRegistrationResponse{}, nil
+}
+
+func (s *serverAPI) BeginPasskeyLogin(ctx context.Context, req *ssov1.BeginPasskeyLoginRequest) (*ssov1.BeginPasskeyLoginResponse, error) {
+	if err := validateBeginPasskeyLogin(req); err != nil {
+		return nil, err
+	}
+
+	options, ceremonyToken, err := s.auth.BeginPasskeyLogin(ctx, req.GetEmail(), int64(req.GetAppId()))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "no passkey for this login")
+		}
+		if errors.Is(err, auth.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		if errors.Is(err, auth.ErrPasskeysDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, "passkeys disabled")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.BeginPasskeyLoginResponse{
+		Options:       string(options),
+		CeremonyToken: ceremonyToken,
+	}, nil
+}
+
+func (s *serverAPI) FinishPasskeyLogin(ctx context.Context, req *ssov1.FinishPasskeyLoginRequest) (*ssov1.FinishPasskeyLoginResponse, error) {
+	if err := validateFinishPasskeyLogin(req); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.FinishPasskeyLogin(ctx, req.GetCeremonyToken(), []byte(req.GetCredential()))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCeremony) {
+			return nil, status.Error(codes.Unauthenticated, "invalid ceremony token")
+		}
+		if errors.Is(err, auth.ErrInvalidPasskey) {
+			return nil, status.Error(codes.Unauthenticated, "invalid credential")
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user disabled")
+		}
+		if errors.Is(err, auth.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		if errors.Is(err, auth.ErrPasskeysDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, "passkeys disabled")
+		}
+		if errors.Is(err, auth.ErrEmailNotVerified) {
+			return nil, status.Error(codes.FailedPrecondition, "email not verified")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.FinishPasskeyLoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		SessionToken: tokens.SessionToken,
+	}, nil
+}
+
+func (s *serverAPI) Refresh(ctx context.Context, req *ssov1.RefreshRequest) (*ssov1.RefreshResponse, error) {
+	if err := validateRefresh(req); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.Refresh(ctx, req.GetRefreshToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			return nil, status.Error(codes.Unauthenticated, "refresh token reuse detected")
+		}
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user disabled")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.RefreshResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
@@ -65,6 +358,84 @@ func (s *serverAPI) Register(ctx context.Context, req *ssov1.RegisterRequest) (*
 	}, nil
 }
 
+func (s *serverAPI) VerifyEmail(ctx context.Context, req *ssov1.VerifyEmailRequest) (*ssov1.VerifyEmailResponse, error) {
+	if err := validateVerifyEmail(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.VerifyEmail(ctx, req.GetToken()); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid verification token")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.VerifyEmailResponse{}, nil
+}
+
+func (s *serverAPI) SendVerificationEmail(ctx context.Context, req *ssov1.SendVerificationEmailRequest) (*ssov1.SendVerificationEmailResponse, error) {
+	if err := validateSendVerificationEmail(req); err != nil {
+		return nil, err
+	}
+
+	s.auth.SendVerificationEmail(ctx, req.GetEmail())
+
+	return &ssov1.SendVerificationEmailResponse{}, nil
+}
+
+func (s *serverAPI) RequestPasswordReset(ctx context.Context, req *ssov1.RequestPasswordResetRequest) (*ssov1.RequestPasswordResetResponse, error) {
+	if err := validateRequestPasswordReset(req); err != nil {
+		return nil, err
+	}
+
+	s.auth.RequestPasswordReset(ctx, req.GetEmail())
+
+	return &ssov1.RequestPasswordResetResponse{}, nil
+}
+
+func (s *serverAPI) ResetPassword(ctx context.Context, req *ssov1.ResetPasswordRequest) (*ssov1.ResetPasswordResponse, error) {
+	if err := validateResetPassword(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.ResetPassword(ctx, req.GetToken(), req.GetPassword()); err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid password reset token")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.ResetPasswordResponse{}, nil
+}
+
+func (s *serverAPI) ChangePassword(ctx context.Context, req *ssov1.ChangePasswordRequest) (*ssov1.ChangePasswordResponse, error) {
+	if err := validateChangePassword(req); err != nil {
+		return nil, err
+	}
+
+	err := s.auth.ChangePassword(ctx, req.GetToken(), req.GetSessionToken(), req.GetCurrentPassword(), req.GetNewPassword(), req.GetKeepSession())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if errors.Is(err, auth.ErrInvalidSession) {
+			return nil, status.Error(codes.Unauthenticated, "invalid session")
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "invalid password")
+		}
+		if errors.Is(err, auth.ErrStepUpRequired) {
+			return nil, status.Error(codes.PermissionDenied, "current password or recent login required")
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user disabled")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.ChangePasswordResponse{}, nil
+}
+
 func (s *serverAPI) IsAdmin(ctx context.Context, req *ssov1.IsAdminRequest) (*ssov1.IsAdminResponse, error) {
 	if err := validateIsAdmin(req); err != nil {
 		return nil, err
@@ -83,6 +454,149 @@ func (s *serverAPI) IsAdmin(ctx context.Context, req *ssov1.IsAdminRequest) (*ss
 	}, nil
 }
 
+func (s *serverAPI) Logout(ctx context.Context, req *ssov1.LogoutRequest) (*ssov1.LogoutResponse, error) {
+	if err := validateLogout(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.Logout(ctx, req.GetToken(), req.GetRefreshToken(), req.GetSessionToken()); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.LogoutResponse{}, nil
+}
+
+func (s *serverAPI) RevokeToken(ctx context.Context, req *ssov1.RevokeTokenRequest) (*ssov1.RevokeTokenResponse, error) {
+	if err := validateRevokeToken(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.RevokeToken(ctx, req.GetToken()); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid token")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.RevokeTokenResponse{}, nil
+}
+
+func (s *serverAPI) IsTokenRevoked(ctx context.Context, req *ssov1.IsTokenRevokedRequest) (*ssov1.IsTokenRevokedResponse, error) {
+	if err := validateIsTokenRevoked(req); err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.auth.IsTokenRevoked(ctx, req.GetTokenId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.IsTokenRevokedResponse{
+		Revoked: revoked,
+	}, nil
+}
+
+func (s *serverAPI) Introspect(ctx context.Context, req *ssov1.IntrospectRequest) (*ssov1.IntrospectResponse, error) {
+	if err := validateIntrospect(req); err != nil {
+		return nil, err
+	}
+
+	info, err := s.auth.Introspect(ctx, req.GetToken())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	if !info.Active {
+		return &ssov1.IntrospectResponse{Active: false}, nil
+	}
+
+	return &ssov1.IntrospectResponse{
+		Active: true,
+		Uid:    info.UID,
+		Email:  info.Email,
+		AppId:  int32(info.AppID),
+		Exp:    info.ExpiresAt.Unix(),
+		Scopes: info.Scopes,
+	}, nil
+}
+
+func (s *serverAPI) RotateSigningKey(ctx context.Context, req *ssov1.RotateSigningKeyRequest) (*ssov1.RotateSigningKeyResponse, error) {
+	if err := s.keys.Rotate(ctx, true); err != nil {
+		if errors.Is(err, keys.ErrRotationUnsupported) {
+			return nil, status.Error(codes.FailedPrecondition, "signing keys are not managed by the service")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.RotateSigningKeyResponse{}, nil
+}
+
+func (s *serverAPI) AssignRole(ctx context.Context, req *ssov1.AssignRoleRequest) (*ssov1.AssignRoleResponse, error) {
+	if err := validateAssignRole(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.AssignRole(ctx, req.GetUserId(), int64(req.GetAppId()), req.GetRole()); err != nil {
+		if errors.Is(err, rbac.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		if errors.Is(err, rbac.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.AssignRoleResponse{}, nil
+}
+
+func (s *serverAPI) RevokeRole(ctx context.Context, req *ssov1.RevokeRoleRequest) (*ssov1.RevokeRoleResponse, error) {
+	if err := validateRevokeRole(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.RevokeRole(ctx, req.GetUserId(), int64(req.GetAppId()), req.GetRole()); err != nil {
+		if errors.Is(err, rbac.ErrRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "role not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.RevokeRoleResponse{}, nil
+}
+
+func (s *serverAPI) GrantPermission(ctx context.Context, req *ssov1.GrantPermissionRequest) (*ssov1.GrantPermissionResponse, error) {
+	if err := validateGrantPermission(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.GrantPermission(ctx, int64(req.GetAppId()), req.GetRole(), req.GetPermission()); err != nil {
+		if errors.Is(err, rbac.ErrAppNotFound) {
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.GrantPermissionResponse{}, nil
+}
+
+func (s *serverAPI) HasPermission(ctx context.Context, req *ssov1.HasPermissionRequest) (*ssov1.HasPermissionResponse, error) {
+	if err := validateHasPermission(req); err != nil {
+		return nil, err
+	}
+
+	has, err := s.rbac.HasPermission(ctx, req.GetUserId(), int64(req.GetAppId()), req.GetPermission())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.HasPermissionResponse{
+		HasPermission: has,
+	}, nil
+}
+
 func validateLogin(req *ssov1.LoginRequest) error {
 	if req.GetEmail() == "" {
 		return status.Error(codes.InvalidArgument, "email required")
@@ -99,6 +613,98 @@ func validateLogin(req *ssov1.LoginRequest) error {
 	return nil
 }
 
+func validateExchangeSession(req *ssov1.ExchangeSessionRequest) error {
+	if req.GetSessionToken() == "" {
+		return status.Error(codes.InvalidArgument, "session_token required")
+	}
+
+	if req.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app required")
+	}
+
+	return nil
+}
+
+func validateEnrollTOTP(req *ssov1.EnrollTOTPRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	return nil
+}
+
+func validateConfirmTOTP(req *ssov1.ConfirmTOTPRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	if req.GetCode() == "" {
+		return status.Error(codes.InvalidArgument, "code required")
+	}
+
+	return nil
+}
+
+func validateVerifyMFA(req *ssov1.VerifyMFARequest) error {
+	if req.GetMfaToken() == "" {
+		return status.Error(codes.InvalidArgument, "mfa_token required")
+	}
+
+	if req.GetCode() == "" {
+		return status.Error(codes.InvalidArgument, "code required")
+	}
+
+	return nil
+}
+
+func validateBeginPasskeyRegistration(req *ssov1.BeginPasskeyRegistrationRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	return nil
+}
+
+func validateFinishPasskeyRegistration(req *ssov1.FinishPasskeyRegistrationRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	if req.GetCeremonyToken() == "" {
+		return status.Error(codes.InvalidArgument, "ceremony_token required")
+	}
+
+	if req.GetCredential() == "" {
+		return status.Error(codes.InvalidArgument, "credential required")
+	}
+
+	return nil
+}
+
+func validateBeginPasskeyLogin(req *ssov1.BeginPasskeyLoginRequest) error {
+	if req.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "email required")
+	}
+
+	if req.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app required")
+	}
+
+	return nil
+}
+
+func validateFinishPasskeyLogin(req *ssov1.FinishPasskeyLoginRequest) error {
+	if req.GetCeremonyToken() == "" {
+		return status.Error(codes.InvalidArgument, "ceremony_token required")
+	}
+
+	if req.GetCredential() == "" {
+		return status.Error(codes.InvalidArgument, "credential required")
+	}
+
+	return nil
+}
+
 func validateRegister(req *ssov1.RegisterRequest) error {
 	if req.GetEmail() == "" {
 		return status.Error(codes.InvalidArgument, "email required")
@@ -111,6 +717,66 @@ func validateRegister(req *ssov1.RegisterRequest) error {
 	return nil
 }
 
+func validateVerifyEmail(req *ssov1.VerifyEmailRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	return nil
+}
+
+func validateSendVerificationEmail(req *ssov1.SendVerificationEmailRequest) error {
+	if req.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "email required")
+	}
+
+	return nil
+}
+
+func validateRequestPasswordReset(req *ssov1.RequestPasswordResetRequest) error {
+	if req.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "email required")
+	}
+
+	return nil
+}
+
+func validateResetPassword(req *ssov1.ResetPasswordRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	if req.GetPassword() == "" {
+		return status.Error(codes.InvalidArgument, "password required")
+	}
+
+	return nil
+}
+
+func validateChangePassword(req *ssov1.ChangePasswordRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	if req.GetNewPassword() == "" {
+		return status.Error(codes.InvalidArgument, "new_password required")
+	}
+
+	if req.GetKeepSession() && req.GetSessionToken() == "" {
+		return status.Error(codes.InvalidArgument, "session_token required to keep the session")
+	}
+
+	return nil
+}
+
+func validateRefresh(req *ssov1.RefreshRequest) error {
+	if req.GetRefreshToken() == "" {
+		return status.Error(codes.InvalidArgument, "refresh_token required")
+	}
+
+	return nil
+}
+
 func validateIsAdmin(req *ssov1.IsAdminRequest) error {
 	if req.GetUserId() == emptyValue {
 		return status.Error(codes.InvalidArgument, "user_id required")
@@ -118,3 +784,91 @@ func validateIsAdmin(req *ssov1.IsAdminRequest) error {
 
 	return nil
 }
+
+func validateLogout(req *ssov1.LogoutRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	return nil
+}
+
+func validateRevokeToken(req *ssov1.RevokeTokenRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	return nil
+}
+
+func validateIsTokenRevoked(req *ssov1.IsTokenRevokedRequest) error {
+	if req.GetTokenId() == "" {
+		return status.Error(codes.InvalidArgument, "token_id required")
+	}
+
+	return nil
+}
+
+func validateIntrospect(req *ssov1.IntrospectRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token required")
+	}
+
+	return nil
+}
+
+func validateAssignRole(req *ssov1.AssignRoleRequest) error {
+	return validateRoleRequest(req.GetUserId(), req.GetAppId(), req.GetRole())
+}
+
+func validateRevokeRole(req *ssov1.RevokeRoleRequest) error {
+	return validateRoleRequest(req.GetUserId(), req.GetAppId(), req.GetRole())
+}
+
+func validateRoleRequest(userID int64, appID int32, role string) error {
+	if userID == emptyValue {
+		return status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	if appID == emptyValue {
+		return status.Error(codes.InvalidArgument, "app required")
+	}
+
+	if role == "" {
+		return status.Error(codes.InvalidArgument, "role required")
+	}
+
+	return nil
+}
+
+func validateGrantPermission(req *ssov1.GrantPermissionRequest) error {
+	if req.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app required")
+	}
+
+	if req.GetRole() == "" {
+		return status.Error(codes.InvalidArgument, "role required")
+	}
+
+	if req.GetPermission() == "" {
+		return status.Error(codes.InvalidArgument, "permission required")
+	}
+
+	return nil
+}
+
+func validateHasPermission(req *ssov1.HasPermissionRequest) error {
+	if req.GetUserId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	if req.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app required")
+	}
+
+	if req.GetPermission() == "" {
+		return status.Error(codes.InvalidArgument, "permission required")
+	}
+
+	return nil
+}
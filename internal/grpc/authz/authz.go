@@ -0,0 +1,183 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"sso/internal/lib/bearer"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/services/auth"
+	"strconv"
+)
+
+// Metadata keys a relying app authenticates itself with.
+const (
+	AppIDHeader     = "x-app-id"
+	AppSecretHeader = "x-app-secret"
+)
+
+// Access is the level of authentication an RPC requires.
+type Access int
+
+const (
+	// Public RPCs can be called by anyone.
+	Public Access = iota
+	// App RPCs require the credentials of a registered app or an admin bearer token.
+	App
+	// Admin RPCs require the bearer token of an admin.
+	Admin
+)
+
+// Policy maps full gRPC method names to the access they require. Methods
+// missing from the policy are rejected.
+type Policy map[string]Access
+
+type Authenticator interface {
+	AuthenticateApp(ctx context.Context, appID int64, secret string) error
+	RequireAdmin(ctx context.Context, token string) (adminID int64, err error)
+}
+
+// Caller identifies who an authorized request came from. Only one of
+// AppID and AdminID is set; both are zero for public RPCs.
+type Caller struct {
+	AppID   int64
+	AdminID int64
+}
+
+type callerKey struct{}
+
+// CallerFromContext returns the caller stored by the interceptors.
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+func UnaryServerInterceptor(log *slog.Logger, policy Policy, authenticator Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, log, policy, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor(log *slog.Logger, policy Policy, authenticator Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), log, policy, authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func authorize(ctx context.Context, log *slog.Logger, policy Policy, authenticator Authenticator, method string) (context.Context, error) {
+	const op = "grpc.authz.authorize"
+
+	log = log.With(slog.String("op", op), slog.String("method", method))
+
+	access, ok := policy[method]
+	if !ok {
+		log.Error("method has no access policy")
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	var (
+		caller Caller
+		err    error
+	)
+
+	switch access {
+	case Public:
+		return ctx, nil
+	case App:
+		if _, _, hasAppCredentials := appCredentials(ctx); hasAppCredentials {
+			caller, err = authenticateApp(ctx, authenticator)
+		} else if bearer.FromIncomingContext(ctx) != "" {
+			caller, err = requireAdmin(ctx, authenticator)
+		} else {
+			err = status.Error(codes.Unauthenticated, "app credentials required")
+		}
+	case Admin:
+		caller, err = requireAdmin(ctx, authenticator)
+	}
+
+	if err != nil {
+		if status.Code(err) == codes.Internal {
+			log.Error("failed to authorize caller", sl.Err(err))
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+		return nil, err
+	}
+
+	return context.WithValue(ctx, callerKey{}, caller), nil
+}
+
+func authenticateApp(ctx context.Context, authenticator Authenticator) (Caller, error) {
+	rawAppID, secret, _ := appCredentials(ctx)
+
+	appID, err := strconv.ParseInt(rawAppID, 10, 64)
+	if err != nil {
+		return Caller{}, status.Error(codes.Unauthenticated, "invalid app credentials")
+	}
+
+	if err := authenticator.AuthenticateApp(ctx, appID, secret); err != nil {
+		if errors.Is(err, auth.ErrInvalidAppCredentials) {
+			return Caller{}, status.Error(codes.Unauthenticated, "invalid app credentials")
+		}
+		return Caller{}, status.Error(codes.Internal, err.Error())
+	}
+
+	return Caller{AppID: appID}, nil
+}
+
+func requireAdmin(ctx context.Context, authenticator Authenticator) (Caller, error) {
+	token := bearer.FromIncomingContext(ctx)
+	if token == "" {
+		return Caller{}, status.Error(codes.Unauthenticated, "bearer token required")
+	}
+
+	adminID, err := authenticator.RequireAdmin(ctx, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return Caller{}, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return Caller{}, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return Caller{}, status.Error(codes.Internal, err.Error())
+	}
+
+	return Caller{AdminID: adminID}, nil
+}
+
+// appCredentials returns the app ID and secret headers if both are present.
+func appCredentials(ctx context.Context) (appID string, secret string, ok bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", "", false
+	}
+
+	ids, secrets := md.Get(AppIDHeader), md.Get(AppSecretHeader)
+	if len(ids) == 0 || len(secrets) == 0 {
+		return "", "", false
+	}
+
+	return ids[0], secrets[0], true
+}
@@ -0,0 +1,112 @@
+package introspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/services/auth"
+	"strconv"
+	"strings"
+)
+
+type Introspector interface {
+	Introspect(ctx context.Context, token string) (models.Introspection, error)
+	AuthenticateApp(ctx context.Context, appID int64, secret string) error
+}
+
+// response follows RFC 7662, section 2.2. uid, email and app_id mirror the
+// claims of the token for clients that already read them from the JWT.
+type response struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+	UID       int64  `json:"uid,omitempty"`
+	Email     string `json:"email,omitempty"`
+	AppID     int    `json:"app_id,omitempty"`
+}
+
+type handler struct {
+	log          *slog.Logger
+	introspector Introspector
+}
+
+func Register(mux *http.ServeMux, log *slog.Logger, introspector Introspector) {
+	h := &handler{log: log, introspector: introspector}
+
+	mux.HandleFunc("POST /introspect", h.introspect)
+}
+
+func (h *handler) introspect(w http.ResponseWriter, r *http.Request) {
+	const op = "http.introspect"
+
+	log := h.log.With(slog.String("op", op))
+
+	// Callers authenticate with HTTP Basic, using the app ID as the user
+	// name and the app secret as the password.
+	rawAppID, secret, ok := r.BasicAuth()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="sso"`)
+		http.Error(w, "app credentials required", http.StatusUnauthorized)
+		return
+	}
+
+	appID, err := strconv.ParseInt(rawAppID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid app credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.introspector.AuthenticateApp(r.Context(), appID, secret); err != nil {
+		if errors.Is(err, auth.ErrInvalidAppCredentials) {
+			http.Error(w, "invalid app credentials", http.StatusUnauthorized)
+			return
+		}
+		log.Error("failed to authenticate app", sl.Err(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		http.Error(w, "token required", http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.introspector.Introspect(r.Context(), token)
+	if err != nil {
+		log.Error("failed to introspect token", sl.Err(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	res := response{Active: info.Active}
+	if info.Active {
+		res.Scope = strings.Join(info.Scopes, " ")
+		res.Subject = strconv.FormatInt(info.UID, 10)
+		res.TokenType = "access_token"
+		res.Exp = info.ExpiresAt.Unix()
+		res.JTI = info.TokenID
+		res.UID = info.UID
+		res.Email = info.Email
+		res.AppID = info.AppID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Error("failed to write response", sl.Err(err))
+	}
+}
@@ -0,0 +1,45 @@
+package wellknown
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sso/internal/lib/jwk"
+	"sso/internal/lib/logger/sl"
+)
+
+const jwksMaxAge = "max-age=300"
+
+type Keys interface {
+	JWKS(ctx context.Context) (jwk.Set, error)
+}
+
+type handler struct {
+	log  *slog.Logger
+	keys Keys
+}
+
+func Register(mux *http.ServeMux, log *slog.Logger, keys Keys) {
+	h := &handler{log: log, keys: keys}
+
+	mux.HandleFunc("GET /.well-known/jwks.json", h.jwks)
+}
+
+func (h *handler) jwks(w http.ResponseWriter, r *http.Request) {
+	const op = "http.wellknown.jwks"
+
+	set, err := h.keys.JWKS(r.Context())
+	if err != nil {
+		h.log.Error("failed to build JWKS", slog.String("op", op), sl.Err(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", jwksMaxAge)
+
+	if err := json.NewEncoder(w).Encode(set); err != nil {
+		h.log.Error("failed to write JWKS", slog.String("op", op), sl.Err(err))
+	}
+}
@@ -0,0 +1,29 @@
+package bearer
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+const (
+	header = "authorization"
+	scheme = "bearer "
+)
+
+// FromIncomingContext returns the token of the "authorization: Bearer <token>"
+// gRPC metadata header, or an empty string if there is none.
+func FromIncomingContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, v := range md.Get(header) {
+		if len(v) > len(scheme) && strings.EqualFold(v[:len(scheme)], scheme) {
+			return strings.TrimSpace(v[len(scheme):])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,183 @@
+// Package envelope implements envelope encryption: values are sealed with a
+// data key, and data keys are stored wrapped by a master key that never
+// touches the database.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of master and data keys. Both are AES-256 keys.
+const KeySize = 32
+
+var (
+	ErrInvalidKey       = errors.New("key must be 32 bytes encoded as base64")
+	ErrUnknownMasterKey = errors.New("unknown master key")
+	ErrMalformed        = errors.New("malformed ciphertext")
+)
+
+// Keyring holds the primary master key, which wraps new data keys, and
+// previous master keys, which are only used to unwrap data keys that have
+// not been re-wrapped yet.
+type Keyring struct {
+	primaryID string
+	keys      map[string][]byte
+}
+
+// NewKeyring returns a keyring with primary as its primary master key.
+func NewKeyring(primary []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte, len(previous)+1)}
+
+	for _, key := range append([][]byte{primary}, previous...) {
+		if len(key) != KeySize {
+			return nil, ErrInvalidKey
+		}
+		k.keys[keyID(key)] = key
+	}
+
+	k.primaryID = keyID(primary)
+
+	return k, nil
+}
+
+// LoadKeyring builds a keyring from configuration. The primary master key is
+// given either inline as base64 or as the path of a file holding it;
+// previous master keys are given as base64.
+func LoadKeyring(masterKey, masterKeyFile string, previousMasterKeys []string) (*Keyring, error) {
+	var (
+		primary []byte
+		err     error
+	)
+
+	switch {
+	case masterKey != "" && masterKeyFile != "":
+		return nil, errors.New("master key and master key file are mutually exclusive")
+	case masterKey != "":
+		primary, err = ParseKey(masterKey)
+	case masterKeyFile != "":
+		primary, err = ReadKeyFile(masterKeyFile)
+	default:
+		return nil, errors.New("master key is not configured")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("master key: %w", err)
+	}
+
+	previous := make([][]byte, 0, len(previousMasterKeys))
+	for _, encoded := range previousMasterKeys {
+		key, err := ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("previous master key: %w", err)
+		}
+		previous = append(previous, key)
+	}
+
+	return NewKeyring(primary, previous...)
+}
+
+// PrimaryID identifies the primary master key. It is stored next to every
+// data key it wraps.
+func (k *Keyring) PrimaryID() string {
+	return k.primaryID
+}
+
+// Wrap encrypts dataKey with the primary master key.
+func (k *Keyring) Wrap(dataKey []byte) ([]byte, error) {
+	return Seal(k.keys[k.primaryID], dataKey)
+}
+
+// Unwrap decrypts a data key wrapped by the master key masterKeyID.
+func (k *Keyring) Unwrap(wrapped []byte, masterKeyID string) ([]byte, error) {
+	key, ok := k.keys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMasterKey, masterKeyID)
+	}
+
+	return Open(key, wrapped)
+}
+
+// NewDataKey generates a random data key.
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Seal encrypts plaintext with AES-GCM under key. The random nonce is
+// prepended to the ciphertext.
+func Seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts a value produced by Seal.
+func Open(key, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// ParseKey decodes a base64-encoded key.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// ReadKeyFile reads a base64-encoded key from path.
+func ReadKeyFile(path string) ([]byte, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseKey(string(encoded))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// keyID is a fingerprint of a master key that does not reveal the key.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
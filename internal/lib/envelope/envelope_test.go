@@ -0,0 +1,75 @@
+package envelope_test
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/internal/lib/envelope"
+	"testing"
+)
+
+func TestSealOpen_RoundTrip(t *testing.T) {
+	key := newKey(t)
+
+	sealed, err := envelope.Seal(key, []byte("app secret"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "app secret")
+
+	opened, err := envelope.Open(key, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "app secret", string(opened))
+
+	// Each seal uses a fresh nonce.
+	again, err := envelope.Seal(key, []byte("app secret"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	_, err = envelope.Open(newKey(t), sealed)
+	assert.Error(t, err)
+
+	_, err = envelope.Open(key, sealed[:4])
+	assert.ErrorIs(t, err, envelope.ErrMalformed)
+}
+
+func TestKeyring_UnwrapWithPreviousMasterKey(t *testing.T) {
+	oldMaster, newMaster := newKey(t), newKey(t)
+
+	oldKeyring, err := envelope.NewKeyring(oldMaster)
+	require.NoError(t, err)
+
+	dataKey := newKey(t)
+	wrapped, err := oldKeyring.Wrap(dataKey)
+	require.NoError(t, err)
+
+	keyring, err := envelope.NewKeyring(newMaster, oldMaster)
+	require.NoError(t, err)
+	require.NotEqual(t, oldKeyring.PrimaryID(), keyring.PrimaryID())
+
+	unwrapped, err := keyring.Unwrap(wrapped, oldKeyring.PrimaryID())
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	// Once the old master key is dropped, its data keys cannot be unwrapped.
+	newOnly, err := envelope.NewKeyring(newMaster)
+	require.NoError(t, err)
+
+	_, err = newOnly.Unwrap(wrapped, oldKeyring.PrimaryID())
+	assert.ErrorIs(t, err, envelope.ErrUnknownMasterKey)
+}
+
+func TestNewKeyring_InvalidKey(t *testing.T) {
+	_, err := envelope.NewKeyring(bytes.Repeat([]byte{1}, envelope.KeySize-1))
+	assert.ErrorIs(t, err, envelope.ErrInvalidKey)
+
+	_, err = envelope.NewKeyring(newKey(t), []byte("short"))
+	assert.ErrorIs(t, err, envelope.ErrInvalidKey)
+}
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := envelope.NewDataKey()
+	require.NoError(t, err)
+
+	return key
+}
@@ -1,24 +1,265 @@
 package jwt
 
 import (
+	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"sso/internal/domain/models"
+	"sso/internal/lib/opaque"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Claims versions select which claims NewToken puts in a token. Version 1
+// tokens carry the legacy uid and app_id claims next to the registered ones
+// so that verifiers written against them keep working; version 2 tokens
+// carry only the registered claims and azp.
+const (
+	ClaimsV1 = 1
+	ClaimsV2 = 2
+)
+
+var (
+	ErrUnsupportedClaimsVersion = errors.New("unsupported claims version")
+	ErrMalformedClaims          = errors.New("malformed claims")
+	ErrUnsupportedAlgorithm     = errors.New("unsupported signing algorithm")
+)
+
+type Claims struct {
+	ID        string
+	UID       int64
+	Email     string
+	AppID     int
+	ExpiresAt time.Time
+	Scopes    []string
+	Roles     []string
+}
+
+// SigningKey is the key a token is signed with. Key is the app secret as
+// []byte for HS256 and a crypto.Signer otherwise. A non-empty ID is put in
+// the kid header so verifiers can pick the matching public key.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Key       any
+}
+
+// Options are the service-wide settings of issued tokens. Issuer is the iss
+// claim of tokens for apps that do not set their own.
+type Options struct {
+	Issuer        string
+	ClaimsVersion int
+}
+
+// KeySet can be returned by the key function passed to Parse when a token
+// may have been signed with any of several keys, such as the current and
+// previous secrets of an app.
+type KeySet []any
+
+// NewToken issues an access token for user in app that is valid for duration.
+// It carries the registered claims iss, sub, aud, iat, nbf, exp and jti, and
+// the ID of app in azp. The issuer and audience are those of app if set, and
+// otherwise opts.Issuer and the app ID. The scopes of app are put in scope,
+// space-separated as in OAuth 2.0, unless there are none. With ClaimsV1 the
+// token also carries uid and app_id. The optional claims app asks for are added on top; roles
+// are the names of the roles user has in app and are omitted if empty.
+func NewToken(user models.User, app models.App, roles []string, duration time.Duration, key SigningKey, opts Options) (string, error) {
+	if opts.ClaimsVersion != ClaimsV1 && opts.ClaimsVersion != ClaimsV2 {
+		return "", fmt.Errorf("%w: %d", ErrUnsupportedClaimsVersion, opts.ClaimsVersion)
+	}
+
+	method := jwt.GetSigningMethod(key.Algorithm)
+	if method == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, key.Algorithm)
+	}
+
+	jti, err := opaque.New()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.New(method)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	issuer := opts.Issuer
+	if app.Issuer != "" {
+		issuer = app.Issuer
+	}
+
+	appID := strconv.Itoa(app.ID)
+	audience := appID
+	if app.Audience != "" {
+		audience = app.Audience
+	}
+
+	now := time.Now()
 
 	claims := token.Claims.(jwt.MapClaims)
-	claims["uid"] = user.ID
-	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
-	claims["app_id"] = app.ID
+	claims["jti"] = jti
+	claims["sub"] = strconv.FormatInt(user.ID, 10)
+	claims["aud"] = audience
+	claims["azp"] = appID
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(duration).Unix()
+	if issuer != "" {
+		claims["iss"] = issuer
+	}
+	if len(app.Scopes) > 0 {
+		claims["scope"] = strings.Join(app.Scopes, " ")
+	}
+
+	if opts.ClaimsVersion == ClaimsV1 {
+		claims["uid"] = user.ID
+		claims["app_id"] = app.ID
+	}
+
+	for _, claim := range app.TokenClaims() {
+		switch claim {
+		case models.ClaimEmail:
+			claims["email"] = user.Email
+		case models.ClaimIsAdmin:
+			claims["is_admin"] = user.IsAdmin
+		case models.ClaimRoles:
+			if len(roles) > 0 {
+				claims["roles"] = roles
+			}
+		}
+	}
 
-	tokenString, err := token.SignedString([]byte(app.Secret))
+	tokenString, err := token.SignedString(key.Key)
 	if err != nil {
 		return "", err
 	}
 
 	return tokenString, nil
 }
+
+// Parse verifies the signature, expiry and not-before time of tokenString,
+// which must be signed with algorithm: accepting any other would let holders
+// of an app secret mint tokens that pass for service-signed ones. keyFunc is
+// called with the kid header (empty for app secrets) and the ID of
+// the app the token was issued for, taken from app_id or else azp, and must
+// return the matching verification key. Tokens of both claims versions are
+// accepted.
+func Parse(tokenString string, algorithm string, keyFunc func(kid string, appID int) (any, error)) (Claims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, ErrMalformedClaims
+		}
+
+		appID, err := appIDFromMap(claims)
+		if err != nil {
+			return nil, err
+		}
+
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := keyFunc(kid, appID)
+		if err != nil {
+			return nil, err
+		}
+
+		if set, ok := key.(KeySet); ok {
+			keys := make([]jwt.VerificationKey, 0, len(set))
+			for _, k := range set {
+				keys = append(keys, k)
+			}
+			return jwt.VerificationKeySet{Keys: keys}, nil
+		}
+
+		return key, nil
+	}, jwt.WithValidMethods([]string{algorithm}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return claimsFromMap(token.Claims.(jwt.MapClaims))
+}
+
+func claimsFromMap(m jwt.MapClaims) (Claims, error) {
+	jti, ok := m["jti"].(string)
+	if !ok {
+		return Claims{}, fmt.Errorf("%w: jti", ErrMalformedClaims)
+	}
+
+	uid, err := uidFromMap(m)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	appID, err := appIDFromMap(m)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	email, _ := m["email"].(string)
+	scope, _ := m["scope"].(string)
+
+	exp, err := m.GetExpirationTime()
+	if err != nil {
+		return Claims{}, err
+	}
+
+	var roles []string
+	if raw, ok := m["roles"].([]interface{}); ok {
+		for _, r := range raw {
+			if role, ok := r.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	return Claims{
+		ID:        jti,
+		UID:       uid,
+		Email:     email,
+		AppID:     appID,
+		ExpiresAt: exp.Time,
+		Scopes:    strings.Fields(scope),
+		Roles:     roles,
+	}, nil
+}
+
+// uidFromMap returns the user ID from the uid claim of version 1 tokens or
+// else from sub.
+func uidFromMap(m jwt.MapClaims) (int64, error) {
+	if uid, ok := m["uid"].(float64); ok {
+		return int64(uid), nil
+	}
+
+	sub, _ := m["sub"].(string)
+	uid, err := strconv.ParseInt(sub, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: sub", ErrMalformedClaims)
+	}
+
+	return uid, nil
+}
+
+// appIDFromMap returns the app ID from the app_id claim of version 1 tokens
+// or else from azp.
+func appIDFromMap(m jwt.MapClaims) (int, error) {
+	if appID, ok := m["app_id"].(float64); ok {
+		return int(appID), nil
+	}
+
+	azp, _ := m["azp"].(string)
+	appID, err := strconv.Atoi(azp)
+	if err != nil {
+		return 0, fmt.Errorf("%w: azp", ErrMalformedClaims)
+	}
+
+	return appID, nil
+}
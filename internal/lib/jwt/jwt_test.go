@@ -0,0 +1,59 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"testing"
+	"time"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	user := models.User{ID: 7, Email: "user@example.com"}
+	app := models.App{ID: 1, Scopes: []string{"invoices:read", "invoices:write"}}
+
+	token, err := jwt.NewToken(user, app, []string{"editor"}, time.Minute,
+		jwt.SigningKey{ID: "kid-1", Algorithm: jwt.AlgES256, Key: key},
+		jwt.Options{Issuer: "sso", ClaimsVersion: jwt.ClaimsV2})
+	require.NoError(t, err)
+
+	claims, err := jwt.Parse(token, jwt.AlgES256, func(kid string, appID int) (any, error) {
+		assert.Equal(t, "kid-1", kid)
+		assert.Equal(t, 1, appID)
+		return key.Public(), nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(7), claims.UID)
+	assert.Equal(t, "user@example.com", claims.Email)
+	assert.Equal(t, []string{"editor"}, claims.Roles)
+	assert.Equal(t, []string{"invoices:read", "invoices:write"}, claims.Scopes)
+}
+
+func TestParse_RejectsOtherAlgorithm(t *testing.T) {
+	secret := []byte("app-secret")
+
+	token, err := jwt.NewToken(models.User{ID: 1}, models.App{ID: 1}, nil, time.Minute,
+		jwt.SigningKey{Algorithm: jwt.AlgHS256, Key: secret},
+		jwt.Options{ClaimsVersion: jwt.ClaimsV1})
+	require.NoError(t, err)
+
+	// The key function hands out the secret, yet the token must not verify
+	// because the service signs with RS256.
+	_, err = jwt.Parse(token, jwt.AlgRS256, func(string, int) (any, error) {
+		return secret, nil
+	})
+	require.Error(t, err)
+
+	_, err = jwt.Parse(token, jwt.AlgHS256, func(string, int) (any, error) {
+		return secret, nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,55 @@
+package totp_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sso/internal/lib/totp"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the test vectors in RFC 6238, Appendix B.
+var rfcSecret = []byte("12345678901234567890")
+
+// The RFC lists eight-digit codes; six-digit codes are their last six digits.
+func TestCode_RFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			at := time.Unix(tt.unix, 0)
+
+			assert.Equal(t, tt.code, totp.Code(rfcSecret, totp.Step(at)))
+
+			step, ok := totp.Verify(rfcSecret, tt.code, at)
+			assert.True(t, ok)
+			assert.Equal(t, totp.Step(at), step)
+		})
+	}
+}
+
+func TestVerify_Skew(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+	code := totp.Code(rfcSecret, totp.Step(at))
+
+	_, ok := totp.Verify(rfcSecret, code, at.Add(totp.Period))
+	assert.True(t, ok, "code of the previous step")
+
+	_, ok = totp.Verify(rfcSecret, code, at.Add(-totp.Period))
+	assert.True(t, ok, "code of the next step")
+
+	_, ok = totp.Verify(rfcSecret, code, at.Add(2*totp.Period))
+	assert.False(t, ok, "code two steps old")
+
+	_, ok = totp.Verify(rfcSecret, code[1:], at)
+	assert.False(t, ok, "code too short")
+}
@@ -0,0 +1,297 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/storage"
+	"time"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+var (
+	ErrAppNotFound  = errors.New("app not found")
+	ErrAppExists    = errors.New("app already exists")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// Admin implements the management operations of the Admin gRPC service.
+// Callers are expected to have been authorized as admins already.
+type Admin struct {
+	log               *slog.Logger
+	appManager        AppManager
+	userManager       UserManager
+	secretGracePeriod time.Duration
+}
+
+type AppManager interface {
+	SaveApp(ctx context.Context, app models.App) error
+	Apps(ctx context.Context) ([]models.App, error)
+	UpdateApp(ctx context.Context, app models.App) error
+	DeleteApp(ctx context.Context, appID int64) error
+	RotateAppSecret(ctx context.Context, appID int64, secret string, retireAt time.Time) error
+	RetireAppSecrets(ctx context.Context, appID int64) (int64, error)
+}
+
+type UserManager interface {
+	Users(ctx context.Context, afterID int64, limit int) ([]models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+	SetAdmin(ctx context.Context, userID int64, isAdmin bool) error
+	SetUserDisabled(ctx context.Context, userID int64, disabled bool) error
+	DeleteUser(ctx context.Context, userID int64) error
+	RevokeUserSessions(ctx context.Context, userID int64) error
+}
+
+// New returns an Admin. secretGracePeriod is how long a rotated app secret
+// stays valid when the caller does not ask for another period.
+func New(log *slog.Logger, appManager AppManager, userManager UserManager, secretGracePeriod time.Duration) *Admin {
+	return &Admin{
+		log:               log,
+		appManager:        appManager,
+		userManager:       userManager,
+		secretGracePeriod: secretGracePeriod,
+	}
+}
+
+// CreateApp registers an app. If app.Secret is empty a random secret is
+// generated. The created app, including its secret, is returned. The token
+// settings of app (TTL, scopes, issuer, audience, claims and the email
+// verification requirement) are not exposed by the Admin API; they are set
+// only through the registry file applied by cmd/registry.
+func (a *Admin) CreateApp(ctx context.Context, app models.App) (models.App, error) {
+	const op = "admin.CreateApp"
+
+	log := a.log.With(slog.String("op", op), slog.Int("app_id", app.ID), slog.String("name", app.Name))
+
+	if app.Secret == "" {
+		secret, err := opaque.New()
+		if err != nil {
+			return models.App{}, fmt.Errorf("%s: %w", op, err)
+		}
+		app.Secret = secret
+	}
+
+	if err := a.appManager.SaveApp(ctx, app); err != nil {
+		if errors.Is(err, storage.ErrAppExists) {
+			return models.App{}, fmt.Errorf("%s: %w", op, ErrAppExists)
+		}
+		log.Error("failed to save app", sl.Err(err))
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app created")
+
+	return app, nil
+}
+
+func (a *Admin) ListApps(ctx context.Context) ([]models.App, error) {
+	const op = "admin.ListApps"
+
+	apps, err := a.appManager.Apps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return apps, nil
+}
+
+// UpdateApp renames an app. Its token settings are left untouched; see
+// CreateApp.
+func (a *Admin) UpdateApp(ctx context.Context, app models.App) error {
+	const op = "admin.UpdateApp"
+
+	log := a.log.With(slog.String("op", op), slog.Int("app_id", app.ID))
+
+	if err := a.appManager.UpdateApp(ctx, app); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		log.Error("failed to update app", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app updated")
+
+	return nil
+}
+
+func (a *Admin) DeleteApp(ctx context.Context, appID int64) error {
+	const op = "admin.DeleteApp"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("app_id", appID))
+
+	if err := a.appManager.DeleteApp(ctx, appID); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		log.Error("failed to delete app", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app deleted")
+
+	return nil
+}
+
+// RotateAppSecret generates a new secret for app and makes it the one new
+// tokens are signed with. The previous secrets become verify-only: tokens
+// signed with them and calls authenticated with them are accepted for
+// gracePeriod, or the configured default if gracePeriod is zero. The new
+// secret and the time the old ones expire are returned.
+func (a *Admin) RotateAppSecret(ctx context.Context, appID int64, gracePeriod time.Duration) (string, time.Time, error) {
+	const op = "admin.RotateAppSecret"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("app_id", appID))
+
+	if gracePeriod <= 0 {
+		gracePeriod = a.secretGracePeriod
+	}
+
+	secret, err := opaque.New()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	retireAt := time.Now().Add(gracePeriod)
+
+	if err := a.appManager.RotateAppSecret(ctx, appID, secret, retireAt); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		log.Error("failed to rotate app secret", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app secret rotated", slog.Time("retire_at", retireAt))
+
+	return secret, retireAt, nil
+}
+
+// RetireAppSecrets drops the verify-only secrets of app before their grace
+// period ends, e.g. once every client has picked up the rotated secret.
+// Tokens signed with them stop being accepted. The number of retired
+// secrets is returned, or ErrAppNotFound if there is no such app.
+func (a *Admin) RetireAppSecrets(ctx context.Context, appID int64) (int64, error) {
+	const op = "admin.RetireAppSecrets"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("app_id", appID))
+
+	retired, err := a.appManager.RetireAppSecrets(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return 0, fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		log.Error("failed to retire app secrets", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app secrets retired", slog.Int64("count", retired))
+
+	return retired, nil
+}
+
+// ListUsers returns a page of users with IDs greater than afterID. A pageSize
+// of zero selects the default page size.
+func (a *Admin) ListUsers(ctx context.Context, afterID int64, pageSize int) ([]models.User, error) {
+	const op = "admin.ListUsers"
+
+	switch {
+	case pageSize <= 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	users, err := a.userManager.Users(ctx, afterID, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func (a *Admin) GetUser(ctx context.Context, userID int64) (models.User, error) {
+	const op = "admin.GetUser"
+
+	user, err := a.userManager.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (a *Admin) SetAdmin(ctx context.Context, userID int64, isAdmin bool) error {
+	const op = "admin.SetAdmin"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", userID))
+
+	if err := a.userManager.SetAdmin(ctx, userID, isAdmin); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to set admin", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("admin flag changed", slog.Bool("is_admin", isAdmin))
+
+	return nil
+}
+
+// DisableUser blocks or unblocks login for userID. Disabling a user also ends
+// their SSO sessions and revokes their refresh tokens; their access tokens are
+// rejected from then on.
+func (a *Admin) DisableUser(ctx context.Context, userID int64, disabled bool) error {
+	const op = "admin.DisableUser"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", userID))
+
+	if err := a.userManager.SetUserDisabled(ctx, userID, disabled); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to disable user", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if disabled {
+		if err := a.userManager.RevokeUserSessions(ctx, userID); err != nil {
+			log.Error("failed to revoke sessions", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Info("user disabled flag changed", slog.Bool("disabled", disabled))
+
+	return nil
+}
+
+func (a *Admin) DeleteUser(ctx context.Context, userID int64) error {
+	const op = "admin.DeleteUser"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", userID))
+
+	if err := a.userManager.DeleteUser(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to delete user", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user deleted")
+
+	return nil
+}
@@ -6,24 +6,59 @@ import (
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"log/slog"
+	"slices"
 	"sso/internal/domain/models"
 	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
 	"sso/internal/storage"
+	"sync"
 	"time"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUserAlreadyExists  = errors.New("user already exists")
-	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrUserAlreadyExists     = errors.New("user already exists")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrInvalidRefreshToken   = errors.New("invalid refresh token")
+	ErrRefreshTokenReused    = errors.New("refresh token reused")
+	ErrInvalidToken          = errors.New("invalid token")
+	ErrPermissionDenied      = errors.New("permission denied")
+	ErrInvalidSession        = errors.New("invalid session")
+	ErrAppNotFound           = errors.New("app not found")
+	ErrUserDisabled          = errors.New("user disabled")
+	ErrInvalidAppCredentials = errors.New("invalid app credentials")
 )
 
 type Auth struct {
-	log          *slog.Logger
-	userSaver    UserSaver
-	userProvider UserProvider
-	appProvider  AppProvider
-	tokenTTL     time.Duration
+	log                   *slog.Logger
+	userSaver             UserSaver
+	userProvider          UserProvider
+	appProvider           AppProvider
+	refreshTokenProvider  RefreshTokenProvider
+	revokedTokenProvider  RevokedTokenProvider
+	keyProvider           KeyProvider
+	sessionProvider       SessionProvider
+	roleProvider          RoleProvider
+	mfaProvider           MFAProvider
+	passkeyProvider       PasskeyProvider
+	verificationProvider  EmailVerificationProvider
+	passwordResetProvider PasswordResetProvider
+	passwordProvider      PasswordProvider
+	mailer                Mailer
+	tokenTTL              time.Duration
+	refreshTokenTTL       time.Duration
+	sessionTTL            time.Duration
+	sessionIdleTimeout    time.Duration
+	tokenOptions          jwt.Options
+	mfaOptions            MFAOptions
+	passkeyOptions        PasskeyOptions
+	verificationOptions   VerificationOptions
+	passwordResetOptions  PasswordResetOptions
+	passwordOptions       PasswordOptions
+
+	// background tracks the work started by inBackground.
+	background sync.WaitGroup
 }
 
 type UserSaver interface {
@@ -32,6 +67,7 @@ type UserSaver interface {
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
 	IsAdmin(ctx context.Context, userID int64) (bool, error)
 }
 
@@ -39,35 +75,164 @@ type AppProvider interface {
 	App(ctx context.Context, appID int64) (models.App, error)
 }
 
-func NewAuth(log *slog.Logger, userSaver UserSaver, userProvider UserProvider, appProvider AppProvider, tokenTTL time.Duration) *Auth {
-	return &Auth{log: log, userSaver: userSaver, userProvider: userProvider, appProvider: appProvider, tokenTTL: tokenTTL}
+type RefreshTokenProvider interface {
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	RefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID int64, next models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+}
+
+type RevokedTokenProvider interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type KeyProvider interface {
+	SigningKey(ctx context.Context, app models.App) (jwt.SigningKey, error)
+	VerificationKey(ctx context.Context, kid string, app models.App) (any, error)
+	Algorithm() string
+}
+
+type SessionProvider interface {
+	SaveSession(ctx context.Context, session models.Session) error
+	Session(ctx context.Context, tokenHash []byte) (models.Session, error)
+	TouchSession(ctx context.Context, sessionID int64, idleSince time.Time) error
+	RevokeSession(ctx context.Context, sessionID int64) error
 }
 
+type RoleProvider interface {
+	UserRoles(ctx context.Context, userID int64, appID int64) ([]string, error)
+}
+
+type MFAProvider interface {
+	SaveTOTP(ctx context.Context, totp models.TOTP) error
+	TOTP(ctx context.Context, userID int64) (models.TOTP, error)
+	ConfirmTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes [][]byte) error
+	UseTOTPStep(ctx context.Context, userID int64, step int64) error
+	FailTOTP(ctx context.Context, userID int64, at time.Time) error
+	ResetTOTPFailures(ctx context.Context, userID int64) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash []byte) error
+	SaveMFAChallenge(ctx context.Context, challenge models.MFAChallenge) error
+	MFAChallenge(ctx context.Context, tokenHash []byte) (models.MFAChallenge, error)
+	FailMFAChallenge(ctx context.Context, tokenHash []byte) error
+	DeleteMFAChallenge(ctx context.Context, tokenHash []byte) error
+}
+
+type PasskeyProvider interface {
+	SavePasskey(ctx context.Context, passkey models.Passkey) error
+	Passkeys(ctx context.Context, userID int64) ([]models.Passkey, error)
+	UsePasskey(ctx context.Context, passkeyID int64, signCount uint32) error
+	SaveCeremony(ctx context.Context, ceremony models.WebAuthnCeremony) error
+	TakeCeremony(ctx context.Context, tokenHash []byte) (models.WebAuthnCeremony, error)
+}
+
+type EmailVerificationProvider interface {
+	SaveEmailVerification(ctx context.Context, verification models.EmailVerification) error
+	TakeEmailVerification(ctx context.Context, tokenHash []byte) (models.EmailVerification, error)
+	SetEmailVerified(ctx context.Context, userID int64, email string) error
+}
+
+type PasswordResetProvider interface {
+	SavePasswordReset(ctx context.Context, reset models.PasswordReset) error
+	TakePasswordReset(ctx context.Context, tokenHash []byte) (models.PasswordReset, error)
+}
+
+type PasswordProvider interface {
+	ChangePassword(ctx context.Context, change models.PasswordChange) error
+}
+
+// Mailer sends a plain text email.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+func NewAuth(
+	log *slog.Logger,
+	userSaver UserSaver,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	refreshTokenProvider RefreshTokenProvider,
+	revokedTokenProvider RevokedTokenProvider,
+	keyProvider KeyProvider,
+	sessionProvider SessionProvider,
+	roleProvider RoleProvider,
+	mfaProvider MFAProvider,
+	passkeyProvider PasskeyProvider,
+	verificationProvider EmailVerificationProvider,
+	passwordResetProvider PasswordResetProvider,
+	passwordProvider PasswordProvider,
+	mailer Mailer,
+	tokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	sessionTTL time.Duration,
+	sessionIdleTimeout time.Duration,
+	tokenOptions jwt.Options,
+	mfaOptions MFAOptions,
+	passkeyOptions PasskeyOptions,
+	verificationOptions VerificationOptions,
+	passwordResetOptions PasswordResetOptions,
+	passwordOptions PasswordOptions,
+) *Auth {
+	return &Auth{
+		log:                   log,
+		userSaver:             userSaver,
+		userProvider:          userProvider,
+		appProvider:           appProvider,
+		refreshTokenProvider:  refreshTokenProvider,
+		revokedTokenProvider:  revokedTokenProvider,
+		keyProvider:           keyProvider,
+		sessionProvider:       sessionProvider,
+		roleProvider:          roleProvider,
+		mfaProvider:           mfaProvider,
+		passkeyProvider:       passkeyProvider,
+		verificationProvider:  verificationProvider,
+		passwordResetProvider: passwordResetProvider,
+		passwordProvider:      passwordProvider,
+		mailer:                mailer,
+		tokenTTL:              tokenTTL,
+		refreshTokenTTL:       refreshTokenTTL,
+		sessionTTL:            sessionTTL,
+		sessionIdleTimeout:    sessionIdleTimeout,
+		tokenOptions:          tokenOptions,
+		mfaOptions:            mfaOptions,
+		passkeyOptions:        passkeyOptions,
+		verificationOptions:   verificationOptions,
+		passwordResetOptions:  passwordResetOptions,
+		passwordOptions:       passwordOptions,
+	}
+}
+
+// RegisterNewUser creates a user and emails them a token to verify their
+// address with. The user is created even if the email cannot be sent.
 func (auth *Auth) RegisterNewUser(ctx context.Context, email, password string) (int64, error) {
 	const op = "auth.RegisterNewUser"
 
 	log := auth.log.With(slog.String("op", op), slog.String("email", email))
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	passHash, err := auth.hashPassword(password)
 	if err != nil {
-		log.Error("failed to hash password", err.Error())
+		log.Error("failed to hash password", sl.Err(err))
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	id, err := auth.userSaver.SaveUser(ctx, email, passHash)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
-			log.Warn("user already exists", err.Error())
+			log.Warn("user already exists", sl.Err(err))
 			return 0, fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
 		}
-		log.Error("failed to save user", err.Error())
+		log.Error("failed to save user", sl.Err(err))
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 	log.Info("user created", slog.String("email", email))
 
+	if err := auth.sendVerification(ctx, id, email); err != nil {
+		log.Error("failed to send email verification", sl.Err(err))
+	}
+
 	return id, nil
 }
 
-func (auth *Auth) Login(ctx context.Context, email, password string, appID int64) (string, error) {
+func (auth *Auth) Login(ctx context.Context, email, password string, appID int64) (models.Tokens, error) {
 	const op = "auth.Login"
 
 	log := auth.log.With(slog.String("op", op), slog.String("email", email))
@@ -77,31 +242,215 @@ func (auth *Auth) Login(ctx context.Context, email, password string, appID int64
 	user, err := auth.userProvider.User(ctx, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
-			auth.log.Warn("user not found", err.Error())
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+			auth.log.Warn("user not found", sl.Err(err))
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
-		log.Error("failed to get user", err.Error())
-		return "", fmt.Errorf("%s: %w", op, err)
+		log.Error("failed to get user", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		auth.log.Info("invalid password", err.Error())
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		auth.log.Info("invalid password", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if user.Disabled {
+		log.Warn("login attempt of disabled user")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrUserDisabled)
 	}
 
 	app, err := auth.appProvider.App(ctx, appID)
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkEmailVerified(user, app); err != nil {
+		log.Info("login attempt with unverified email")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	mfaToken, err := auth.startMFAChallenge(ctx, user, app)
+	if err != nil {
+		log.Error("failed to start mfa challenge", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if mfaToken != "" {
+		log.Info("second factor required")
+		return models.Tokens{MFAToken: mfaToken}, nil
 	}
 
 	log.Info("user logged in successfully")
 
-	token, err := jwt.NewToken(user, app, auth.tokenTTL)
+	return auth.completeLogin(ctx, log, op, user, app)
+}
+
+// completeLogin issues the tokens for app and starts the SSO session of a user
+// who passed every authentication step.
+func (auth *Auth) completeLogin(ctx context.Context, log *slog.Logger, op string, user models.User, app models.App) (models.Tokens, error) {
+	tokens, err := auth.startTokenFamily(ctx, user, app)
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		log.Error("failed to issue tokens", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens.SessionToken, err = auth.startSession(ctx, user)
+	if err != nil {
+		log.Error("failed to start session", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// startTokenFamily issues an access token and the first refresh token of a
+// new rotation family.
+func (auth *Auth) startTokenFamily(ctx context.Context, user models.User, app models.App) (models.Tokens, error) {
+	familyID, err := opaque.New()
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	tokens, next, err := auth.issueTokens(ctx, user, app, familyID)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	if err := auth.refreshTokenProvider.SaveRefreshToken(ctx, next); err != nil {
+		return models.Tokens{}, err
+	}
+
+	return tokens, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token
+// is rotated: it cannot be used again, and presenting it again revokes every
+// token issued from the same login.
+func (auth *Auth) Refresh(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	const op = "auth.Refresh"
+
+	log := auth.log.With(slog.String("op", op))
+
+	current, err := auth.refreshTokenProvider.RefreshToken(ctx, opaque.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Warn("refresh token not found")
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+		}
+		log.Error("failed to get refresh token", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", current.UserID), slog.String("family", current.FamilyID))
+
+	if current.Revoked {
+		log.Warn("refresh token revoked")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+	}
+
+	if current.Rotated {
+		return models.Tokens{}, auth.revokeReusedFamily(ctx, log, op, current.FamilyID)
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		log.Info("refresh token expired")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+	}
+
+	user, err := auth.userProvider.UserByID(ctx, current.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+		}
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		log.Warn("refresh attempt of disabled user")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrUserDisabled)
+	}
+
+	app, err := auth.appProvider.App(ctx, int64(current.AppID))
+	if err != nil {
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, next, err := auth.issueTokens(ctx, user, app, current.FamilyID)
+	if err != nil {
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := auth.refreshTokenProvider.RotateRefreshToken(ctx, current.ID, next); err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotRotatable) {
+			// Another request rotated the token between our read and write.
+			return models.Tokens{}, auth.revokeReusedFamily(ctx, log, op, current.FamilyID)
+		}
+		log.Error("failed to rotate refresh token", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("refresh token rotated")
+
+	return tokens, nil
+}
+
+func (auth *Auth) revokeReusedFamily(ctx context.Context, log *slog.Logger, op, familyID string) error {
+	log.Warn("refresh token reuse detected, revoking token family")
+
+	if err := auth.refreshTokenProvider.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		log.Error("failed to revoke token family", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrRefreshTokenReused)
+}
+
+// issueTokens creates an access token and a refresh token in the given family.
+// The returned models.RefreshToken holds only the hash and must be persisted by the caller.
+func (auth *Auth) issueTokens(ctx context.Context, user models.User, app models.App, familyID string) (models.Tokens, models.RefreshToken, error) {
+	key, err := auth.keyProvider.SigningKey(ctx, app)
+	if err != nil {
+		return models.Tokens{}, models.RefreshToken{}, err
+	}
+
+	var roles []string
+	if slices.Contains(app.TokenClaims(), models.ClaimRoles) {
+		roles, err = auth.roleProvider.UserRoles(ctx, user.ID, int64(app.ID))
+		if err != nil {
+			return models.Tokens{}, models.RefreshToken{}, err
+		}
+	}
+
+	tokenTTL := auth.tokenTTL
+	if app.TokenTTL > 0 {
+		tokenTTL = app.TokenTTL
+	}
+
+	accessToken, err := jwt.NewToken(user, app, roles, tokenTTL, key, auth.tokenOptions)
+	if err != nil {
+		return models.Tokens{}, models.RefreshToken{}, err
+	}
+
+	refreshToken, err := opaque.New()
+	if err != nil {
+		return models.Tokens{}, models.RefreshToken{}, err
+	}
+
+	tokens := models.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	next := models.RefreshToken{
+		TokenHash: opaque.Hash(refreshToken),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		AppID:     app.ID,
+		ExpiresAt: time.Now().Add(auth.refreshTokenTTL),
 	}
 
-	return token, nil
+	return tokens, next, nil
 }
 
 func (auth *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
@@ -114,6 +463,7 @@ func (auth *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	isAdmin, err := auth.userProvider.IsAdmin(ctx, userID)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
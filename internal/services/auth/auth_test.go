@@ -0,0 +1,110 @@
+package auth_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/services/auth"
+	"sso/internal/services/keys"
+	"sso/internal/storage/memory"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	appID          = 1
+	password       = "password"
+	maxMFAFailures = 3
+)
+
+// newAuth returns an auth service backed by a fresh memory storage with one
+// app, whose tokens are signed with HS256. Emails go to the returned mailer.
+func newAuth(t *testing.T) (*auth.Auth, *memory.Storage, *testMailer) {
+	t.Helper()
+
+	mail := &testMailer{}
+
+	st := memory.New()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	require.NoError(t, st.SaveApp(context.Background(), models.App{ID: appID, Name: "test", Secret: "test-secret"}))
+
+	keyService, err := keys.New(log, st, st, "HS256", time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	a := auth.NewAuth(
+		log,
+		st, st, st, st, st,
+		keyService,
+		st, st, st, st, st, st, st,
+		mail,
+		time.Hour,
+		time.Hour,
+		time.Hour,
+		time.Hour,
+		jwt.Options{Issuer: "sso", ClaimsVersion: 1},
+		auth.MFAOptions{Issuer: "SSO", ChallengeTTL: time.Minute, MaxAttempts: 5, MaxFailures: maxMFAFailures, Lockout: time.Hour},
+		auth.PasskeyOptions{},
+		auth.VerificationOptions{TTL: time.Hour},
+		auth.PasswordResetOptions{TTL: time.Hour},
+		auth.PasswordOptions{HashCost: 4, StepUpMaxAge: time.Minute},
+	)
+
+	return a, st, mail
+}
+
+// registerAndLogin registers a user with password and logs them in.
+func registerAndLogin(t *testing.T, a *auth.Auth, email string) (int64, models.Tokens) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	userID, err := a.RegisterNewUser(ctx, email, password)
+	require.NoError(t, err)
+
+	tokens, err := a.Login(ctx, email, password, appID)
+	require.NoError(t, err)
+
+	return userID, tokens
+}
+
+// testMailer keeps the emails it is asked to send.
+type testMailer struct {
+	mu     sync.Mutex
+	emails []email
+}
+
+type email struct {
+	to, subject, body string
+}
+
+func (m *testMailer) Send(_ context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.emails = append(m.emails, email{to: to, subject: subject, body: body})
+
+	return nil
+}
+
+// tokens returns the tokens of the emails sent to to that ask to use them to
+// do action, oldest first.
+func (m *testMailer) tokens(to, action string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []string
+	for _, e := range m.emails {
+		_, token, ok := strings.Cut(e.body, "Use this code to "+action+":")
+		if e.to == to && ok {
+			tokens = append(tokens, strings.TrimSpace(token))
+		}
+	}
+
+	return tokens
+}
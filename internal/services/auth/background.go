@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// backgroundTimeout bounds work started by inBackground.
+const backgroundTimeout = time.Minute
+
+// inBackground runs fn after the caller has returned, with a context that
+// keeps the values of ctx but is not canceled with it.
+func (auth *Auth) inBackground(ctx context.Context, fn func(ctx context.Context)) {
+	auth.background.Add(1)
+
+	go func() {
+		defer auth.background.Done()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+		defer cancel()
+
+		fn(ctx)
+	}()
+}
+
+// Wait blocks until the work started in the background, such as sending
+// password reset emails, is done. Call it before closing the storage.
+func (auth *Auth) Wait() {
+	auth.background.Wait()
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+)
+
+// Introspect reports whether token is currently active and, if it is, what it
+// was issued for. Invalid, expired and revoked tokens are reported as inactive
+// rather than as an error.
+func (auth *Auth) Introspect(ctx context.Context, token string) (models.Introspection, error) {
+	const op = "auth.Introspect"
+
+	claims, err := auth.authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			auth.log.Debug("inactive token introspected", slog.String("op", op))
+			return models.Introspection{Active: false}, nil
+		}
+		return models.Introspection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.Introspection{
+		Active:    true,
+		TokenID:   claims.ID,
+		UID:       claims.UID,
+		Email:     claims.Email,
+		AppID:     claims.AppID,
+		ExpiresAt: claims.ExpiresAt,
+		Scopes:    claims.Scopes,
+	}, nil
+}
@@ -0,0 +1,31 @@
+package auth_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/internal/domain/models"
+	"testing"
+)
+
+func TestIntrospect_Scopes(t *testing.T) {
+	ctx := context.Background()
+	a, st, _ := newAuth(t)
+
+	_, tokens := registerAndLogin(t, a, "unscoped@example.com")
+
+	info, err := a.Introspect(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, info.Active)
+	assert.Empty(t, info.Scopes)
+
+	scopes := []string{"reports:read", "reports:write"}
+	require.NoError(t, st.UpdateAppSettings(ctx, models.App{ID: appID, Scopes: scopes}))
+
+	_, tokens = registerAndLogin(t, a, "scoped@example.com")
+
+	info, err = a.Introspect(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, info.Active)
+	assert.Equal(t, scopes, info.Scopes)
+}
@@ -0,0 +1,361 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/lib/totp"
+	"sso/internal/storage"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount = 10
+	// recoveryCodeLen is the number of random bytes in a recovery code,
+	// which base32 encodes to ten characters.
+	recoveryCodeLen = 6
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+	ErrMFANotEnrolled    = errors.New("mfa not enrolled")
+	ErrInvalidMFACode    = errors.New("invalid mfa code")
+	ErrInvalidMFAToken   = errors.New("invalid mfa token")
+	ErrMFALocked         = errors.New("too many wrong mfa codes")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAOptions configure the second factor. Issuer names the service in
+// authenticator apps. A login waits for the second factor for ChallengeTTL
+// and fails after MaxAttempts wrong codes. Since every login starts a new
+// challenge, wrong codes are also counted per user: after MaxFailures in a
+// row, across logins, the second factor is refused until Lockout has passed
+// since the last of them.
+type MFAOptions struct {
+	Issuer       string
+	ChallengeTTL time.Duration
+	MaxAttempts  int
+	MaxFailures  int
+	Lockout      time.Duration
+}
+
+// EnrollTOTP starts the enrollment of a TOTP authenticator for the owner of
+// accessToken. The authenticator guards logins only once ConfirmTOTP was
+// called with a code from it; enrolling again before that replaces it.
+func (auth *Auth) EnrollTOTP(ctx context.Context, accessToken string) (models.TOTPEnrollment, error) {
+	const op = "auth.EnrollTOTP"
+
+	log := auth.log.With(slog.String("op", op))
+
+	claims, err := auth.authenticate(ctx, accessToken)
+	if err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	user, err := auth.userProvider.UserByID(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auth.mfaProvider.SaveTOTP(ctx, models.TOTP{
+		UserID:    user.ID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPConfirmed) {
+			log.Warn("totp already enabled")
+			return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, ErrMFAAlreadyEnabled)
+		}
+		log.Error("failed to save totp", sl.Err(err))
+		return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("totp enrollment started")
+
+	return models.TOTPEnrollment{
+		Secret: totp.Encode(secret),
+		URI:    totp.URI(auth.mfaOptions.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the authenticator enrolled by the owner of accessToken
+// once code shows it was set up correctly. It returns the recovery codes that
+// can each complete one login instead of a TOTP code; they are not stored and
+// cannot be shown again.
+func (auth *Auth) ConfirmTOTP(ctx context.Context, accessToken string, code string) ([]string, error) {
+	const op = "auth.ConfirmTOTP"
+
+	log := auth.log.With(slog.String("op", op))
+
+	claims, err := auth.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	enrolled, err := auth.mfaProvider.TOTP(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrMFANotEnrolled)
+		}
+		log.Error("failed to get totp", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !enrolled.ConfirmedAt.IsZero() {
+		return nil, fmt.Errorf("%s: %w", op, ErrMFAAlreadyEnabled)
+	}
+
+	step, ok := totp.Verify(enrolled.Secret, code, time.Now())
+	if !ok {
+		log.Info("invalid totp code")
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidMFACode)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := auth.mfaProvider.ConfirmTOTP(ctx, claims.UID, step, hashes); err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrMFANotEnrolled)
+		}
+		log.Error("failed to confirm totp", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("totp enabled")
+
+	return codes, nil
+}
+
+// VerifyMFA completes the login that returned mfaToken with code, which is
+// either a TOTP code or an unused recovery code.
+func (auth *Auth) VerifyMFA(ctx context.Context, mfaToken string, code string) (models.Tokens, error) {
+	const op = "auth.VerifyMFA"
+
+	log := auth.log.With(slog.String("op", op))
+
+	tokenHash := opaque.Hash(mfaToken)
+
+	challenge, err := auth.mfaProvider.MFAChallenge(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrMFAChallengeNotFound) {
+			log.Warn("mfa challenge not found")
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+		}
+		log.Error("failed to get mfa challenge", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", challenge.UserID), slog.Int64("app_id", challenge.AppID))
+
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= auth.mfaOptions.MaxAttempts {
+		log.Info("mfa challenge is no longer valid")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+	}
+
+	user, err := auth.userProvider.UserByID(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+		}
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		log.Warn("mfa attempt of disabled user")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrUserDisabled)
+	}
+
+	enrolled, err := auth.mfaProvider.TOTP(ctx, user.ID)
+	if err != nil {
+		log.Error("failed to get totp", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if auth.mfaLocked(enrolled) {
+		log.Warn("mfa locked after too many wrong codes", slog.Int("failures", enrolled.Failures))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrMFALocked)
+	}
+
+	ok, err := auth.checkSecondFactor(ctx, enrolled, code)
+	if err != nil {
+		log.Error("failed to check second factor", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !ok {
+		log.Info("invalid mfa code")
+		if err := auth.mfaProvider.FailMFAChallenge(ctx, tokenHash); err != nil {
+			log.Error("failed to record mfa attempt", sl.Err(err))
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := auth.mfaProvider.FailTOTP(ctx, user.ID, time.Now()); err != nil {
+			log.Error("failed to record mfa failure", sl.Err(err))
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+		}
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidMFACode)
+	}
+
+	if enrolled.Failures > 0 {
+		if err := auth.mfaProvider.ResetTOTPFailures(ctx, user.ID); err != nil {
+			log.Error("failed to reset mfa failures", sl.Err(err))
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := auth.mfaProvider.DeleteMFAChallenge(ctx, tokenHash); err != nil {
+		if errors.Is(err, storage.ErrMFAChallengeNotFound) {
+			log.Warn("mfa challenge completed concurrently")
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+		}
+		log.Error("failed to delete mfa challenge", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := auth.appProvider.App(ctx, challenge.AppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("second factor verified")
+
+	return auth.completeLogin(ctx, log, op, user, app)
+}
+
+// startMFAChallenge returns the token of a new MFA challenge for the login of
+// user to app, or an empty token if user has not enabled a second factor.
+func (auth *Auth) startMFAChallenge(ctx context.Context, user models.User, app models.App) (string, error) {
+	enrolled, err := auth.mfaProvider.TOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if enrolled.ConfirmedAt.IsZero() {
+		return "", nil
+	}
+
+	token, err := opaque.New()
+	if err != nil {
+		return "", err
+	}
+
+	err = auth.mfaProvider.SaveMFAChallenge(ctx, models.MFAChallenge{
+		TokenHash: opaque.Hash(token),
+		UserID:    user.ID,
+		AppID:     int64(app.ID),
+		ExpiresAt: time.Now().Add(auth.mfaOptions.ChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// mfaLocked reports whether the owner of enrolled gave too many wrong second
+// factors in a row to try again yet.
+func (auth *Auth) mfaLocked(enrolled models.TOTP) bool {
+	if auth.mfaOptions.MaxFailures <= 0 || enrolled.Failures < auth.mfaOptions.MaxFailures {
+		return false
+	}
+
+	return time.Since(enrolled.LastFailureAt) < auth.mfaOptions.Lockout
+}
+
+// checkSecondFactor reports whether code is a TOTP code of the authenticator
+// enrolled that was not used before or one of its owner's unused recovery
+// codes, and uses it up.
+func (auth *Auth) checkSecondFactor(ctx context.Context, enrolled models.TOTP, code string) (bool, error) {
+	code = normalizeRecoveryCode(code)
+
+	if !isTOTPCode(code) {
+		err := auth.mfaProvider.UseRecoveryCode(ctx, enrolled.UserID, opaque.Hash(code))
+		if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	step, ok := totp.Verify(enrolled.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	err := auth.mfaProvider.UseTOTPStep(ctx, enrolled.UserID, step)
+	if errors.Is(err, storage.ErrTOTPStepUsed) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// newRecoveryCodes returns fresh recovery codes formatted for the user and
+// the hashes to store.
+func newRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([][]byte, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		b := make([]byte, recoveryCodeLen)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, opaque.Hash(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode undoes the formatting of recovery codes so that they
+// may be typed with or without the dash and in any case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
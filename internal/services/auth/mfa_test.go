@@ -0,0 +1,71 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/internal/lib/totp"
+	"sso/internal/services/auth"
+	"testing"
+	"time"
+)
+
+func TestVerifyMFA_LocksOutAcrossLogins(t *testing.T) {
+	ctx := context.Background()
+
+	a, st, _ := newAuth(t)
+	userID, tokens := registerAndLogin(t, a, "user@example.com")
+
+	enrollment, err := a.EnrollTOTP(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	require.NoError(t, err)
+
+	step := totp.Step(time.Now())
+
+	_, err = a.ConfirmTOTP(ctx, tokens.AccessToken, totp.Code(secret, step))
+	require.NoError(t, err)
+
+	// The code of the next step is accepted once the lockout is over; the
+	// code used to confirm cannot be replayed.
+	code := totp.Code(secret, step+1)
+	wrong := "000000"
+	if wrong == code {
+		wrong = "111111"
+	}
+
+	// One wrong code per login: each starts a new challenge, so only the
+	// per-user count can stop the guessing.
+	for range maxMFAFailures {
+		_, err := a.VerifyMFA(ctx, mfaToken(t, a), wrong)
+		require.ErrorIs(t, err, auth.ErrInvalidMFACode)
+	}
+
+	_, err = a.VerifyMFA(ctx, mfaToken(t, a), code)
+	require.ErrorIs(t, err, auth.ErrMFALocked)
+
+	// Once the lockout has passed since the last failure, the right code
+	// gets through and the count starts over.
+	require.NoError(t, st.FailTOTP(ctx, userID, time.Now().Add(-2*time.Hour)))
+
+	_, err = a.VerifyMFA(ctx, mfaToken(t, a), code)
+	require.NoError(t, err)
+
+	enrolled, err := st.TOTP(ctx, userID)
+	require.NoError(t, err)
+	assert.Zero(t, enrolled.Failures)
+}
+
+// mfaToken logs in user@example.com and returns the token of the MFA
+// challenge the login starts.
+func mfaToken(t *testing.T, a *auth.Auth) string {
+	t.Helper()
+
+	tokens, err := a.Login(context.Background(), "user@example.com", password, appID)
+	require.NoError(t, err)
+	require.NotEmpty(t, tokens.MFAToken)
+
+	return tokens.MFAToken
+}
@@ -0,0 +1,437 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/storage"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrPasskeysDisabled = errors.New("passkeys disabled")
+	ErrInvalidPasskey   = errors.New("invalid passkey")
+	ErrInvalidCeremony  = errors.New("invalid ceremony")
+	ErrPasskeyExists    = errors.New("passkey already registered")
+)
+
+// PasskeyOptions configure passkeys. RelyingParty verifies the responses of
+// authenticators; passkeys are disabled if it is nil. A registration or login
+// waits for the authenticator for CeremonyTTL.
+type PasskeyOptions struct {
+	RelyingParty *webauthn.WebAuthn
+	CeremonyTTL  time.Duration
+}
+
+// passkeyUser presents a user and their passkeys to the relying party. The
+// user handle stored on authenticators is the decimal user ID.
+type passkeyUser struct {
+	user     models.User
+	passkeys []models.Passkey
+}
+
+func (u passkeyUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(u.user.ID, 10))
+}
+
+func (u passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Email
+}
+
+func (u passkeyUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+	for _, passkey := range u.passkeys {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(passkey.Transports))
+		for _, transport := range passkey.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              passkey.CredentialID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.CredentialFlags{BackupEligible: passkey.BackupEligible},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
+
+// BeginPasskeyRegistration starts the registration of a passkey for the owner
+// of accessToken. It returns the options to pass to navigator.credentials.create
+// as JSON and the token that identifies the registration in
+// FinishPasskeyRegistration.
+func (auth *Auth) BeginPasskeyRegistration(ctx context.Context, accessToken string) ([]byte, string, error) {
+	const op = "auth.BeginPasskeyRegistration"
+
+	log := auth.log.With(slog.String("op", op))
+
+	if auth.passkeyOptions.RelyingParty == nil {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrPasskeysDisabled)
+	}
+
+	claims, err := auth.authenticate(ctx, accessToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	user, err := auth.passkeyUser(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.passkeys))
+	for _, credential := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := auth.passkeyOptions.RelyingParty.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		log.Error("failed to begin registration", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, token, err := auth.startCeremony(ctx, creation, session, user.user.ID, 0)
+	if err != nil {
+		log.Error("failed to start ceremony", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return options, token, nil
+}
+
+// FinishPasskeyRegistration verifies the response of the authenticator to the
+// registration identified by ceremonyToken and stores the new passkey of the
+// owner of accessToken.
+func (auth *Auth) FinishPasskeyRegistration(ctx context.Context, accessToken, ceremonyToken string, response []byte) error {
+	const op = "auth.FinishPasskeyRegistration"
+
+	log := auth.log.With(slog.String("op", op))
+
+	if auth.passkeyOptions.RelyingParty == nil {
+		return fmt.Errorf("%s: %w", op, ErrPasskeysDisabled)
+	}
+
+	claims, err := auth.authenticate(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	ceremony, session, err := auth.takeCeremony(ctx, ceremonyToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCeremony) {
+			log.Warn("invalid ceremony")
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		log.Error("failed to take ceremony", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if ceremony.UserID != claims.UID || ceremony.AppID != 0 {
+		log.Warn("ceremony of another user or login")
+		return fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+	}
+
+	user, err := auth.passkeyUser(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(response))
+	if err != nil {
+		log.Info("malformed registration response", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, ErrInvalidPasskey)
+	}
+
+	credential, err := auth.passkeyOptions.RelyingParty.CreateCredential(user, session, parsed)
+	if err != nil {
+		log.Info("registration response rejected", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, ErrInvalidPasskey)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	err = auth.passkeyProvider.SavePasskey(ctx, models.Passkey{
+		UserID:          user.user.ID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  credential.Flags.BackupEligible,
+		CreatedAt:       time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrPasskeyExists) {
+			log.Warn("passkey already registered")
+			return fmt.Errorf("%s: %w", op, ErrPasskeyExists)
+		}
+		log.Error("failed to save passkey", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("passkey registered")
+
+	return nil
+}
+
+// BeginPasskeyLogin starts a passwordless login of the user with email to
+// appID. It returns the options to pass to navigator.credentials.get as JSON
+// and the token that identifies the login in FinishPasskeyLogin.
+func (auth *Auth) BeginPasskeyLogin(ctx context.Context, email string, appID int64) ([]byte, string, error) {
+	const op = "auth.BeginPasskeyLogin"
+
+	log := auth.log.With(slog.String("op", op), slog.String("email", email))
+
+	if auth.passkeyOptions.RelyingParty == nil {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrPasskeysDisabled)
+	}
+
+	found, err := auth.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found")
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := auth.passkeyUser(ctx, found.ID)
+	if err != nil {
+		log.Error("failed to get passkeys", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(user.passkeys) == 0 {
+		log.Info("user has no passkeys")
+		return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if _, err := auth.appProvider.App(ctx, appID); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	assertion, session, err := auth.passkeyOptions.RelyingParty.BeginLogin(user)
+	if err != nil {
+		log.Error("failed to begin login", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, token, err := auth.startCeremony(ctx, assertion, session, user.user.ID, appID)
+	if err != nil {
+		log.Error("failed to start ceremony", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return options, token, nil
+}
+
+// FinishPasskeyLogin verifies the response of the authenticator to the login
+// identified by ceremonyToken and logs the user in. A passkey proves both
+// possession of the authenticator and the user's presence or verification, so
+// the login does not ask for a TOTP code. A response whose signature counter
+// did not increase is rejected as it may come from a cloned authenticator.
+func (auth *Auth) FinishPasskeyLogin(ctx context.Context, ceremonyToken string, response []byte) (models.Tokens, error) {
+	const op = "auth.FinishPasskeyLogin"
+
+	log := auth.log.With(slog.String("op", op))
+
+	if auth.passkeyOptions.RelyingParty == nil {
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrPasskeysDisabled)
+	}
+
+	ceremony, session, err := auth.takeCeremony(ctx, ceremonyToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCeremony) {
+			log.Warn("invalid ceremony")
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+		}
+		log.Error("failed to take ceremony", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", ceremony.UserID), slog.Int64("app_id", ceremony.AppID))
+
+	if ceremony.AppID == 0 {
+		log.Warn("ceremony of a registration")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+	}
+
+	user, err := auth.passkeyUser(ctx, ceremony.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidCeremony)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err != nil {
+		log.Info("malformed login response", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidPasskey)
+	}
+
+	credential, err := auth.passkeyOptions.RelyingParty.ValidateLogin(user, session, parsed)
+	if err != nil {
+		log.Info("login response rejected", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidPasskey)
+	}
+
+	if credential.Authenticator.CloneWarning {
+		log.Warn("passkey sign count did not increase")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidPasskey)
+	}
+
+	if user.user.Disabled {
+		log.Warn("login attempt of disabled user")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrUserDisabled)
+	}
+
+	var passkeyID int64
+	for _, passkey := range user.passkeys {
+		if bytes.Equal(passkey.CredentialID, credential.ID) {
+			passkeyID = passkey.ID
+		}
+	}
+
+	if err := auth.passkeyProvider.UsePasskey(ctx, passkeyID, credential.Authenticator.SignCount); err != nil {
+		if errors.Is(err, storage.ErrPasskeySignCount) {
+			log.Warn("passkey sign count did not increase")
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidPasskey)
+		}
+		log.Error("failed to record passkey use", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := auth.appProvider.App(ctx, ceremony.AppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkEmailVerified(user.user, app); err != nil {
+		log.Info("passkey login attempt with unverified email")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in with passkey")
+
+	return auth.completeLogin(ctx, log, op, user.user, app)
+}
+
+func (auth *Auth) passkeyUser(ctx context.Context, userID int64) (passkeyUser, error) {
+	user, err := auth.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		return passkeyUser{}, err
+	}
+
+	passkeys, err := auth.passkeyProvider.Passkeys(ctx, userID)
+	if err != nil {
+		return passkeyUser{}, err
+	}
+
+	return passkeyUser{user: user, passkeys: passkeys}, nil
+}
+
+// startCeremony stores session until the authenticator responds and returns
+// options encoded for the client with the token of the ceremony.
+func (auth *Auth) startCeremony(ctx context.Context, options any, session *webauthn.SessionData, userID, appID int64) ([]byte, string, error) {
+	encodedOptions, err := json.Marshal(options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	encodedSession, err := json.Marshal(session)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := opaque.New()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = auth.passkeyProvider.SaveCeremony(ctx, models.WebAuthnCeremony{
+		TokenHash: opaque.Hash(token),
+		UserID:    userID,
+		AppID:     appID,
+		Session:   encodedSession,
+		ExpiresAt: time.Now().Add(auth.passkeyOptions.CeremonyTTL),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return encodedOptions, token, nil
+}
+
+// takeCeremony returns the unexpired ceremony identified by token and its
+// session, or ErrInvalidCeremony. Each ceremony can be taken only once.
+func (auth *Auth) takeCeremony(ctx context.Context, token string) (models.WebAuthnCeremony, webauthn.SessionData, error) {
+	ceremony, err := auth.passkeyProvider.TakeCeremony(ctx, opaque.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrCeremonyNotFound) {
+			return models.WebAuthnCeremony{}, webauthn.SessionData{}, ErrInvalidCeremony
+		}
+		return models.WebAuthnCeremony{}, webauthn.SessionData{}, err
+	}
+
+	if time.Now().After(ceremony.ExpiresAt) {
+		return models.WebAuthnCeremony{}, webauthn.SessionData{}, ErrInvalidCeremony
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(ceremony.Session, &session); err != nil {
+		return models.WebAuthnCeremony{}, webauthn.SessionData{}, err
+	}
+
+	return ceremony, session, nil
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/storage"
+	"time"
+)
+
+var ErrStepUpRequired = errors.New("current password or recent login required")
+
+// PasswordOptions configure password hashing and changes. HashCost is the
+// bcrypt cost of new hashes. A session started within StepUpMaxAge lets its
+// user change their password without giving the current one.
+type PasswordOptions struct {
+	HashCost     int
+	StepUpMaxAge time.Duration
+}
+
+// ChangePassword sets the password of the owner of accessToken to
+// newPassword. They prove it is them with currentPassword or, if that is
+// empty, with sessionToken of a session they started within the step-up age.
+// Their other sessions and all of their refresh tokens are revoked; the
+// session of sessionToken is revoked too unless keepSession is set. The new
+// password, the revocations and an audit event that records how they proved
+// it is them are stored at once.
+func (auth *Auth) ChangePassword(ctx context.Context, accessToken, sessionToken, currentPassword, newPassword string, keepSession bool) error {
+	const op = "auth.ChangePassword"
+
+	log := auth.log.With(slog.String("op", op))
+
+	claims, err := auth.authenticate(ctx, accessToken)
+	if err != nil {
+		log.Warn("invalid access token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	user, err := auth.userProvider.UserByID(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		log.Warn("password change of disabled user")
+		return fmt.Errorf("%s: %w", op, ErrUserDisabled)
+	}
+
+	var session models.Session
+	if sessionToken != "" {
+		if session, err = auth.userSession(ctx, user.ID, sessionToken); err != nil {
+			if errors.Is(err, ErrInvalidSession) {
+				log.Warn("invalid session")
+			} else {
+				log.Error("failed to get session", sl.Err(err))
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	var method string
+	switch {
+	case currentPassword != "":
+		if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(currentPassword)); err != nil {
+			log.Info("invalid current password")
+			return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		method = models.AuditMethodCurrentPassword
+	case sessionToken != "" && time.Since(session.CreatedAt) <= auth.passwordOptions.StepUpMaxAge:
+		method = models.AuditMethodRecentLogin
+	default:
+		log.Info("password change without current password or recent login")
+		return fmt.Errorf("%s: %w", op, ErrStepUpRequired)
+	}
+
+	passHash, err := auth.hashPassword(newPassword)
+	if err != nil {
+		log.Error("failed to hash password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	change := models.PasswordChange{
+		UserID:   user.ID,
+		PassHash: passHash,
+		Event: models.AuditEvent{
+			Action:    models.AuditPasswordChanged,
+			Method:    method,
+			CreatedAt: time.Now(),
+		},
+	}
+	if keepSession && sessionToken != "" {
+		change.KeepSessionID = session.ID
+	}
+
+	if err := auth.passwordProvider.ChangePassword(ctx, change); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		log.Error("failed to change password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password changed", slog.String("method", method), slog.Bool("kept_session", keepSession))
+
+	return nil
+}
+
+// userSession returns the session of sessionToken if it is still valid and
+// belongs to userID, and ErrInvalidSession otherwise.
+func (auth *Auth) userSession(ctx context.Context, userID int64, sessionToken string) (models.Session, error) {
+	session, err := auth.sessionProvider.Session(ctx, opaque.Hash(sessionToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return models.Session{}, ErrInvalidSession
+		}
+		return models.Session{}, err
+	}
+
+	now := time.Now()
+	idleSince := now.Add(-auth.sessionIdleTimeout)
+
+	if session.UserID != userID || session.Revoked || now.After(session.ExpiresAt) || session.LastSeenAt.Before(idleSince) {
+		return models.Session{}, ErrInvalidSession
+	}
+
+	return session, nil
+}
+
+// hashPassword hashes password with the configured cost.
+func (auth *Auth) hashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), auth.passwordOptions.HashCost)
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/storage"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+// PasswordResetOptions configure password resets. Tokens are valid for TTL.
+// If URL is set the email links to it with the token in the token query
+// parameter; otherwise it only contains the token.
+type PasswordResetOptions struct {
+	TTL time.Duration
+	URL string
+}
+
+// RequestPasswordReset emails a password reset token to email if it belongs
+// to an enabled user. Whether it does is never revealed to the caller: the
+// user is looked up and the email sent after the call has returned, so that
+// neither its result nor its duration depends on them, and failures are only
+// logged.
+func (auth *Auth) RequestPasswordReset(ctx context.Context, email string) {
+	auth.inBackground(ctx, func(ctx context.Context) {
+		auth.requestPasswordReset(ctx, email)
+	})
+}
+
+func (auth *Auth) requestPasswordReset(ctx context.Context, email string) {
+	const op = "auth.RequestPasswordReset"
+
+	log := auth.log.With(slog.String("op", op))
+
+	user, err := auth.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("password reset requested for unknown email")
+			return
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return
+	}
+
+	log = log.With(slog.Int64("uid", user.ID))
+
+	if user.Disabled {
+		log.Info("password reset requested for disabled user")
+		return
+	}
+
+	if err := auth.sendPasswordReset(ctx, user); err != nil {
+		log.Error("failed to send password reset", sl.Err(err))
+		return
+	}
+
+	log.Info("password reset sent")
+}
+
+// ResetPassword sets the password of the user token was sent to, revokes
+// their sessions and refresh tokens and discards their other password reset
+// tokens.
+func (auth *Auth) ResetPassword(ctx context.Context, token, password string) error {
+	const op = "auth.ResetPassword"
+
+	log := auth.log.With(slog.String("op", op))
+
+	reset, err := auth.passwordResetProvider.TakePasswordReset(ctx, opaque.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrPasswordResetNotFound) {
+			log.Warn("password reset not found")
+			return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+		log.Error("failed to get password reset", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", reset.UserID))
+
+	if time.Now().After(reset.ExpiresAt) {
+		log.Info("password reset expired")
+		return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+	}
+
+	passHash, err := auth.hashPassword(password)
+	if err != nil {
+		log.Error("failed to hash password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = auth.passwordProvider.ChangePassword(ctx, models.PasswordChange{
+		UserID:   reset.UserID,
+		PassHash: passHash,
+		Event: models.AuditEvent{
+			Action:    models.AuditPasswordReset,
+			Method:    models.AuditMethodResetToken,
+			CreatedAt: time.Now(),
+		},
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user deleted since the password reset was sent")
+			return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+		log.Error("failed to change password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset")
+
+	return nil
+}
+
+// sendPasswordReset emails a new password reset token to user.
+func (auth *Auth) sendPasswordReset(ctx context.Context, user models.User) error {
+	token, err := opaque.New()
+	if err != nil {
+		return err
+	}
+
+	err = auth.passwordResetProvider.SavePasswordReset(ctx, models.PasswordReset{
+		TokenHash: opaque.Hash(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(auth.passwordResetOptions.TTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := tokenEmail(auth.passwordResetOptions.URL, token, "reset your password")
+	if err != nil {
+		return err
+	}
+
+	return auth.mailer.Send(ctx, user.Email, "Reset your password", body)
+}
@@ -0,0 +1,71 @@
+package auth_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/internal/domain/models"
+	"sso/internal/services/auth"
+	"testing"
+)
+
+const resetAction = "reset your password"
+
+func TestResetPassword_DiscardsOtherTokens(t *testing.T) {
+	ctx := context.Background()
+
+	a, st, mail := newAuth(t)
+	userID, _ := registerAndLogin(t, a, "user@example.com")
+
+	a.RequestPasswordReset(ctx, "user@example.com")
+	a.RequestPasswordReset(ctx, "user@example.com")
+	a.Wait()
+
+	tokens := mail.tokens("user@example.com", resetAction)
+	require.Len(t, tokens, 2)
+
+	require.NoError(t, a.ResetPassword(ctx, tokens[0], "new-password"))
+
+	// The reset discards every other token of the user, not only the one used.
+	err := a.ResetPassword(ctx, tokens[1], "other-password")
+	assert.ErrorIs(t, err, auth.ErrInvalidResetToken)
+
+	_, err = a.Login(ctx, "user@example.com", "new-password", appID)
+	assert.NoError(t, err)
+
+	events, err := st.AuditEvents(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditPasswordReset, events[0].Action)
+	assert.Equal(t, models.AuditMethodResetToken, events[0].Method)
+}
+
+func TestRequestPasswordReset_SendsNothingToUnknownOrDisabledUsers(t *testing.T) {
+	ctx := context.Background()
+
+	a, st, mail := newAuth(t)
+	userID, _ := registerAndLogin(t, a, "disabled@example.com")
+	require.NoError(t, st.SetUserDisabled(ctx, userID, true))
+
+	a.RequestPasswordReset(ctx, "unknown@example.com")
+	a.RequestPasswordReset(ctx, "disabled@example.com")
+	a.Wait()
+
+	assert.Empty(t, mail.tokens("unknown@example.com", resetAction))
+	assert.Empty(t, mail.tokens("disabled@example.com", resetAction))
+}
+
+func TestRequestPasswordReset_OutlivesRequestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	a, _, mail := newAuth(t)
+	registerAndLogin(t, a, "user@example.com")
+
+	// The email is sent after the call returns, even if the request that
+	// asked for it is over by then.
+	a.RequestPasswordReset(ctx, "user@example.com")
+	cancel()
+	a.Wait()
+
+	assert.Len(t, mail.tokens("user@example.com", resetAction), 1)
+}
@@ -0,0 +1,75 @@
+package auth_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/internal/domain/models"
+	"sso/internal/services/auth"
+	"testing"
+)
+
+func TestChangePassword_RecordsAuditEvent(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		currentPassword string
+		useSession      bool
+		expectedMethod  string
+	}{
+		{
+			name:            "Current password",
+			currentPassword: password,
+			expectedMethod:  models.AuditMethodCurrentPassword,
+		},
+		{
+			name:           "Recent login",
+			useSession:     true,
+			expectedMethod: models.AuditMethodRecentLogin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, st, _ := newAuth(t)
+			userID, tokens := registerAndLogin(t, a, "user@example.com")
+
+			var sessionToken string
+			if tt.useSession {
+				sessionToken = tokens.SessionToken
+			}
+
+			err := a.ChangePassword(ctx, tokens.AccessToken, sessionToken, tt.currentPassword, "new-password", false)
+			require.NoError(t, err)
+
+			events, err := st.AuditEvents(ctx, userID)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, models.AuditPasswordChanged, events[0].Action)
+			assert.Equal(t, tt.expectedMethod, events[0].Method)
+			assert.False(t, events[0].CreatedAt.IsZero())
+
+			_, err = a.ExchangeSession(ctx, tokens.SessionToken, appID)
+			assert.ErrorIs(t, err, auth.ErrInvalidSession)
+		})
+	}
+}
+
+func TestChangePassword_FailureRecordsNothing(t *testing.T) {
+	ctx := context.Background()
+
+	a, st, _ := newAuth(t)
+	userID, tokens := registerAndLogin(t, a, "user@example.com")
+
+	err := a.ChangePassword(ctx, tokens.AccessToken, "", "wrong", "new-password", false)
+	require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+	events, err := st.AuditEvents(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// The session survives a failed attempt.
+	_, err = a.ExchangeSession(ctx, tokens.SessionToken, appID)
+	assert.NoError(t, err)
+}
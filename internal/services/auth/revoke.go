@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/storage"
+)
+
+// Logout revokes accessToken and, if given, the refresh token family it was
+// issued with and the SSO session. A refresh token or session that belongs to
+// another user is ignored.
+func (auth *Auth) Logout(ctx context.Context, accessToken, refreshToken, sessionToken string) error {
+	const op = "auth.Logout"
+
+	log := auth.log.With(slog.String("op", op))
+
+	claims, err := auth.authenticate(ctx, accessToken)
+	if err != nil {
+		log.Info("invalid access token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	if err := auth.revokedTokenProvider.RevokeToken(ctx, claims.ID, claims.ExpiresAt); err != nil {
+		log.Error("failed to revoke access token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if refreshToken != "" {
+		token, err := auth.refreshTokenProvider.RefreshToken(ctx, opaque.Hash(refreshToken))
+		switch {
+		case errors.Is(err, storage.ErrRefreshTokenNotFound):
+			log.Warn("refresh token not found")
+		case err != nil:
+			log.Error("failed to get refresh token", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		case token.UserID != claims.UID:
+			log.Warn("refresh token belongs to another user")
+		default:
+			if err := auth.refreshTokenProvider.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+				log.Error("failed to revoke refresh tokens", sl.Err(err))
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	if sessionToken != "" {
+		if err := auth.revokeSession(ctx, log, claims.UID, sessionToken); err != nil {
+			log.Error("failed to revoke session", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Info("user logged out")
+
+	return nil
+}
+
+// RevokeToken puts token on the denylist. The caller must have been
+// authorized as an admin.
+func (auth *Auth) RevokeToken(ctx context.Context, token string) error {
+	const op = "auth.RevokeToken"
+
+	log := auth.log.With(slog.String("op", op))
+
+	claims, err := auth.parseToken(ctx, token)
+	if err != nil {
+		log.Info("invalid token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := auth.revokedTokenProvider.RevokeToken(ctx, claims.ID, claims.ExpiresAt); err != nil {
+		log.Error("failed to revoke token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("token revoked", slog.Int64("uid", claims.UID), slog.String("jti", claims.ID))
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the token with the given jti has been revoked.
+func (auth *Auth) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	const op = "auth.IsTokenRevoked"
+
+	revoked, err := auth.revokedTokenProvider.IsTokenRevoked(ctx, tokenID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/storage"
+	"time"
+)
+
+// ExchangeSession issues tokens for appID to the owner of sessionToken without
+// asking for a password. The session must be neither expired nor idle for
+// longer than the idle timeout; using it resets the idle timer.
+func (auth *Auth) ExchangeSession(ctx context.Context, sessionToken string, appID int64) (models.Tokens, error) {
+	const op = "auth.ExchangeSession"
+
+	log := auth.log.With(slog.String("op", op), slog.Int64("app_id", appID))
+
+	session, err := auth.sessionProvider.Session(ctx, opaque.Hash(sessionToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			log.Warn("session not found")
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidSession)
+		}
+		log.Error("failed to get session", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", session.UserID))
+
+	now := time.Now()
+	idleSince := now.Add(-auth.sessionIdleTimeout)
+
+	if session.Revoked || now.After(session.ExpiresAt) || session.LastSeenAt.Before(idleSince) {
+		log.Info("session is no longer valid")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidSession)
+	}
+
+	if err := auth.sessionProvider.TouchSession(ctx, session.ID, idleSince); err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidSession)
+		}
+		log.Error("failed to touch session", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := auth.userProvider.UserByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidSession)
+		}
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Disabled {
+		log.Warn("session exchange of disabled user")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrUserDisabled)
+	}
+
+	app, err := auth.appProvider.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkEmailVerified(user, app); err != nil {
+		log.Info("session exchange with unverified email")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := auth.startTokenFamily(ctx, user, app)
+	if err != nil {
+		log.Error("failed to issue tokens", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("session exchanged for app tokens")
+
+	return tokens, nil
+}
+
+// startSession creates an SSO session for user and returns its token.
+func (auth *Auth) startSession(ctx context.Context, user models.User) (string, error) {
+	token, err := opaque.New()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	err = auth.sessionProvider.SaveSession(ctx, models.Session{
+		TokenHash: opaque.Hash(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(auth.sessionTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// revokeSession ends the session identified by sessionToken if it belongs to userID.
+func (auth *Auth) revokeSession(ctx context.Context, log *slog.Logger, userID int64, sessionToken string) error {
+	session, err := auth.sessionProvider.Session(ctx, opaque.Hash(sessionToken))
+	switch {
+	case errors.Is(err, storage.ErrSessionNotFound):
+		log.Warn("session not found")
+		return nil
+	case err != nil:
+		return err
+	case session.UserID != userID:
+		log.Warn("session belongs to another user")
+		return nil
+	}
+
+	return auth.sessionProvider.RevokeSession(ctx, session.ID)
+}
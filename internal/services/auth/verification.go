@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/storage"
+	"time"
+)
+
+var (
+	ErrEmailNotVerified         = errors.New("email not verified")
+	ErrInvalidVerificationToken = errors.New("invalid verification token")
+)
+
+// VerificationOptions configure email verification. Tokens are valid for TTL.
+// If URL is set the email links to it with the token in the token query
+// parameter; otherwise it only contains the token.
+type VerificationOptions struct {
+	TTL time.Duration
+	URL string
+}
+
+// VerifyEmail marks the address that token was sent to as verified.
+func (auth *Auth) VerifyEmail(ctx context.Context, token string) error {
+	const op = "auth.VerifyEmail"
+
+	log := auth.log.With(slog.String("op", op))
+
+	verification, err := auth.verificationProvider.TakeEmailVerification(ctx, opaque.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrEmailVerificationNotFound) {
+			log.Warn("email verification not found")
+			return fmt.Errorf("%s: %w", op, ErrInvalidVerificationToken)
+		}
+		log.Error("failed to get email verification", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", verification.UserID))
+
+	if time.Now().After(verification.ExpiresAt) {
+		log.Info("email verification expired")
+		return fmt.Errorf("%s: %w", op, ErrInvalidVerificationToken)
+	}
+
+	if err := auth.verificationProvider.SetEmailVerified(ctx, verification.UserID, verification.Email); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user gone or email changed since the verification was sent")
+			return fmt.Errorf("%s: %w", op, ErrInvalidVerificationToken)
+		}
+		log.Error("failed to mark email verified", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email verified")
+
+	return nil
+}
+
+// SendVerificationEmail emails a new verification token to email if it
+// belongs to an enabled user whose address is not verified yet, for users
+// whose first email expired or got lost. Like RequestPasswordReset it does so
+// after the call has returned and never reveals whether it did.
+func (auth *Auth) SendVerificationEmail(ctx context.Context, email string) {
+	auth.inBackground(ctx, func(ctx context.Context) {
+		auth.sendVerificationEmail(ctx, email)
+	})
+}
+
+func (auth *Auth) sendVerificationEmail(ctx context.Context, email string) {
+	const op = "auth.SendVerificationEmail"
+
+	log := auth.log.With(slog.String("op", op))
+
+	user, err := auth.userProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("verification email requested for unknown email")
+			return
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return
+	}
+
+	log = log.With(slog.Int64("uid", user.ID))
+
+	switch {
+	case user.Disabled:
+		log.Info("verification email requested for disabled user")
+		return
+	case user.EmailVerified:
+		log.Info("verification email requested for verified email")
+		return
+	}
+
+	if err := auth.sendVerification(ctx, user.ID, user.Email); err != nil {
+		log.Error("failed to send verification email", sl.Err(err))
+		return
+	}
+
+	log.Info("verification email sent")
+}
+
+// sendVerification emails a new verification token to the address of user.
+func (auth *Auth) sendVerification(ctx context.Context, userID int64, email string) error {
+	token, err := opaque.New()
+	if err != nil {
+		return err
+	}
+
+	err = auth.verificationProvider.SaveEmailVerification(ctx, models.EmailVerification{
+		TokenHash: opaque.Hash(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(auth.verificationOptions.TTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := tokenEmail(auth.verificationOptions.URL, token, "verify your email address")
+	if err != nil {
+		return err
+	}
+
+	return auth.mailer.Send(ctx, email, "Verify your email address", body)
+}
+
+// tokenEmail returns the body of an email that asks the recipient to use
+// token to do action. If rawURL is set the email also links to it with the
+// token in the token query parameter.
+func tokenEmail(rawURL, token, action string) (string, error) {
+	if rawURL == "" {
+		return fmt.Sprintf("Use this code to %s:\n\n%s\n", action, token), nil
+	}
+
+	link, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return fmt.Sprintf("Open this link to %s:\n\n%s\n\nOr enter this code:\n\n%s\n", action, link, token), nil
+}
+
+// checkEmailVerified returns ErrEmailNotVerified if app only admits users with
+// a verified email and user is not one of them.
+func checkEmailVerified(user models.User, app models.App) error {
+	if app.RequireVerifiedEmail && !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
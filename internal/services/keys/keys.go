@@ -0,0 +1,444 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwk"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/opaque"
+	"sso/internal/storage"
+	"sync"
+	"time"
+)
+
+const rsaKeyBits = 2048
+
+var (
+	ErrKeyNotFound         = errors.New("signing key not found")
+	ErrRotationUnsupported = errors.New("key rotation requires an asymmetric signing algorithm")
+)
+
+// Keys hands out the keys tokens are signed and verified with. With the
+// HS256 algorithm tokens are signed with the active app secret; with RS256, ES256
+// and EdDSA they are signed with a key pair managed by the service, whose
+// public half is published as a JWKS document.
+//
+// Managed keys are rotated every rotationInterval. A retired key stays in the
+// JWKS document for the longest access-token TTL, tokenTTL or that of any
+// app, so that every token it signed can be verified until it expires.
+type Keys struct {
+	log              *slog.Logger
+	keyStorage       KeyStorage
+	appProvider      AppProvider
+	algorithm        string
+	rotationInterval time.Duration
+	tokenTTL         time.Duration
+
+	mu     sync.RWMutex
+	loaded bool
+	keys   map[string]key
+}
+
+type KeyStorage interface {
+	SaveSigningKey(ctx context.Context, key models.SigningKey) error
+	SigningKeys(ctx context.Context) ([]models.SigningKey, error)
+	RotateSigningKeys(ctx context.Context, promote string, retireAt time.Time, next models.SigningKey) error
+	RetireSigningKeys(ctx context.Context) (int64, error)
+}
+
+type AppProvider interface {
+	Apps(ctx context.Context) ([]models.App, error)
+}
+
+type key struct {
+	algorithm   string
+	state       string
+	signer      crypto.Signer
+	activatedAt time.Time
+}
+
+func New(log *slog.Logger, keyStorage KeyStorage, appProvider AppProvider, algorithm string, rotationInterval, tokenTTL time.Duration) (*Keys, error) {
+	switch algorithm {
+	case jwt.AlgHS256, jwt.AlgRS256, jwt.AlgES256, jwt.AlgEdDSA:
+	default:
+		return nil, fmt.Errorf("%w: %s", jwt.ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	return &Keys{
+		log:              log,
+		keyStorage:       keyStorage,
+		appProvider:      appProvider,
+		algorithm:        algorithm,
+		rotationInterval: rotationInterval,
+		tokenTTL:         tokenTTL,
+		keys:             make(map[string]key),
+	}, nil
+}
+
+// Algorithm returns the algorithm tokens are signed with. Tokens signed with
+// any other algorithm are rejected.
+func (k *Keys) Algorithm() string {
+	return k.algorithm
+}
+
+// Managed reports whether tokens are signed with service-managed keys.
+func (k *Keys) Managed() bool {
+	return k.algorithm != jwt.AlgHS256
+}
+
+// SigningKey returns the key new tokens for app are signed with. In
+// asymmetric mode an active key pair is generated on first use.
+func (k *Keys) SigningKey(ctx context.Context, app models.App) (jwt.SigningKey, error) {
+	const op = "keys.SigningKey"
+
+	if !k.Managed() {
+		if app.Secret == "" {
+			return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, ErrKeyNotFound)
+		}
+		return jwt.SigningKey{Algorithm: jwt.AlgHS256, Key: []byte(app.Secret)}, nil
+	}
+
+	if err := k.ensureLoaded(ctx); err != nil {
+		return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	kid, active, ok := k.newest(models.KeyStateActive)
+	if !ok {
+		generated, err := k.generate(ctx, models.KeyStateActive)
+		if err != nil {
+			return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := k.reload(ctx); err != nil {
+			return jwt.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		kid, active = generated.ID, k.keys[generated.ID]
+	}
+
+	return jwt.SigningKey{ID: kid, Algorithm: active.algorithm, Key: active.signer}, nil
+}
+
+// VerificationKey returns the key a token with the given kid header must be
+// verified with. With HS256, tokens without a kid are verified with any of the
+// app secrets that have not expired, so tokens signed before a secret
+// rotation remain valid during its grace period. With managed keys a kid is
+// required: app secrets are known to the apps and must not verify tokens.
+func (k *Keys) VerificationKey(ctx context.Context, kid string, app models.App) (any, error) {
+	const op = "keys.VerificationKey"
+
+	if kid == "" {
+		if k.Managed() {
+			return nil, fmt.Errorf("%s: %w", op, ErrKeyNotFound)
+		}
+
+		if len(app.Secrets) == 0 {
+			return nil, fmt.Errorf("%s: %w", op, ErrKeyNotFound)
+		}
+
+		set := make(jwt.KeySet, 0, len(app.Secrets))
+		for _, secret := range app.Secrets {
+			set = append(set, []byte(secret.Secret))
+		}
+		return set, nil
+	}
+
+	if err := k.ensureLoaded(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	k.mu.RLock()
+	found, ok := k.keys[kid]
+	k.mu.RUnlock()
+
+	if !ok {
+		// The key may have been created by another replica.
+		if err := k.reload(ctx); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		k.mu.RLock()
+		found, ok = k.keys[kid]
+		k.mu.RUnlock()
+
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", op, ErrKeyNotFound)
+		}
+	}
+
+	return found.signer.Public(), nil
+}
+
+// JWKS returns the public keys tokens may be verified with: pending keys that
+// are about to be activated, the active key and retiring keys.
+func (k *Keys) JWKS(ctx context.Context) (jwk.Set, error) {
+	const op = "keys.JWKS"
+
+	if err := k.reload(ctx); err != nil {
+		return jwk.Set{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := jwk.Set{Keys: make([]jwk.Key, 0, len(k.keys))}
+	for kid, key := range k.keys {
+		pub, err := jwk.New(kid, key.algorithm, key.signer.Public())
+		if err != nil {
+			return jwk.Set{}, fmt.Errorf("%s: %w", op, err)
+		}
+		set.Keys = append(set.Keys, pub)
+	}
+
+	return set, nil
+}
+
+// Rotate activates the pending key, retires the active one and publishes a new
+// pending key. Unless force is set, nothing happens before the active key is
+// rotationInterval old; a pending key is still created if there is none.
+// Retiring keys past their retirement time are retired either way.
+func (k *Keys) Rotate(ctx context.Context, force bool) error {
+	const op = "keys.Rotate"
+
+	log := k.log.With(slog.String("op", op))
+
+	if !k.Managed() {
+		return fmt.Errorf("%s: %w", op, ErrRotationUnsupported)
+	}
+
+	retired, err := k.keyStorage.RetireSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if retired > 0 {
+		log.Info("signing keys retired", slog.Int64("count", retired))
+	}
+
+	if err := k.reload(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, active, hasActive := k.newest(models.KeyStateActive)
+	pendingID, _, hasPending := k.newest(models.KeyStatePending)
+
+	due := !hasActive || force || time.Since(active.activatedAt) >= k.rotationInterval
+	if !due {
+		if hasPending {
+			return nil
+		}
+
+		if _, err := k.generate(ctx, models.KeyStatePending); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return k.reload(ctx)
+	}
+
+	if !hasPending {
+		pending, err := k.generate(ctx, models.KeyStatePending)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		pendingID = pending.ID
+	}
+
+	next, err := k.newKey(models.KeyStatePending)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	retention, err := k.retention(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = k.keyStorage.RotateSigningKeys(ctx, pendingID, time.Now().Add(retention), next)
+	if err != nil {
+		if errors.Is(err, storage.ErrSigningKeyNotFound) {
+			log.Info("signing keys already rotated by another instance")
+			return k.reload(ctx)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("signing keys rotated", slog.String("active", pendingID), slog.String("pending", next.ID))
+
+	return k.reload(ctx)
+}
+
+// retention returns how long a retired key must stay verifiable: the longest
+// lifetime of an access token of any app.
+func (k *Keys) retention(ctx context.Context) (time.Duration, error) {
+	apps, err := k.appProvider.Apps(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	retention := k.tokenTTL
+	for _, app := range apps {
+		retention = max(retention, app.TokenTTL)
+	}
+
+	return retention, nil
+}
+
+// newest returns the most recently activated (or created) key in state that
+// uses the configured algorithm.
+func (k *Keys) newest(state string) (string, key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var (
+		newestID string
+		newest   key
+		found    bool
+	)
+
+	for kid, key := range k.keys {
+		if key.state != state || key.algorithm != k.algorithm {
+			continue
+		}
+		if !found || key.activatedAt.After(newest.activatedAt) {
+			newestID, newest, found = kid, key, true
+		}
+	}
+
+	return newestID, newest, found
+}
+
+func (k *Keys) ensureLoaded(ctx context.Context) error {
+	k.mu.RLock()
+	loaded := k.loaded
+	k.mu.RUnlock()
+
+	if loaded {
+		return nil
+	}
+
+	return k.reload(ctx)
+}
+
+func (k *Keys) reload(ctx context.Context) error {
+	stored, err := k.keyStorage.SigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]key, len(stored))
+
+	for _, s := range stored {
+		parsed, err := x509.ParsePKCS8PrivateKey(s.PrivateKey)
+		if err != nil {
+			k.log.Error("failed to parse signing key", slog.String("kid", s.ID), sl.Err(err))
+			continue
+		}
+
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			k.log.Error("signing key is not a signer", slog.String("kid", s.ID))
+			continue
+		}
+
+		activatedAt := s.ActivatedAt
+		if activatedAt.IsZero() {
+			activatedAt = s.CreatedAt
+		}
+
+		keys[s.ID] = key{
+			algorithm:   s.Algorithm,
+			state:       s.State,
+			signer:      signer,
+			activatedAt: activatedAt,
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.loaded = true
+	k.mu.Unlock()
+
+	return nil
+}
+
+// generate creates a key pair in the given state and saves it.
+func (k *Keys) generate(ctx context.Context, state string) (models.SigningKey, error) {
+	stored, err := k.newKey(state)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	if err := k.keyStorage.SaveSigningKey(ctx, stored); err != nil {
+		return models.SigningKey{}, err
+	}
+
+	k.log.Info("signing key generated",
+		slog.String("kid", stored.ID),
+		slog.String("alg", k.algorithm),
+		slog.String("state", state),
+	)
+
+	return stored, nil
+}
+
+func (k *Keys) newKey(state string) (models.SigningKey, error) {
+	signer, err := generateSigner(k.algorithm)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	kid, err := opaque.New()
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	now := time.Now()
+
+	stored := models.SigningKey{
+		ID:         kid,
+		Algorithm:  k.algorithm,
+		State:      state,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+		CreatedAt:  now,
+	}
+	if state == models.KeyStateActive {
+		stored.ActivatedAt = now
+	}
+
+	return stored, nil
+}
+
+func generateSigner(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case jwt.AlgRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case jwt.AlgES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.AlgEdDSA:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return nil, fmt.Errorf("%w: %s", jwt.ErrUnsupportedAlgorithm, algorithm)
+	}
+}
@@ -0,0 +1,217 @@
+package keys_test
+
+import (
+	"context"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/services/keys"
+	"sso/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+const appSecret = "app-secret"
+
+var testApp = models.App{
+	ID:      1,
+	Name:    "test",
+	Secret:  appSecret,
+	Secrets: []models.AppSecret{{Secret: appSecret, State: models.AppSecretActive}},
+}
+
+func newKeys(t *testing.T, algorithm string) *keys.Keys {
+	t.Helper()
+
+	k, _ := newKeysWithStorage(t, algorithm, time.Hour)
+
+	return k
+}
+
+// newKeysWithStorage returns keys that keep retired keys verifiable for
+// tokenTTL, and the storage they are kept in.
+func newKeysWithStorage(t *testing.T, algorithm string, tokenTTL time.Duration) (*keys.Keys, *memory.Storage) {
+	t.Helper()
+
+	st := memory.New()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	k, err := keys.New(log, st, st, algorithm, time.Hour, tokenTTL)
+	require.NoError(t, err)
+
+	return k, st
+}
+
+// parse verifies token the way the auth service does.
+func parse(ctx context.Context, k *keys.Keys, token string) (jwt.Claims, error) {
+	return jwt.Parse(token, k.Algorithm(), func(kid string, _ int) (any, error) {
+		return k.VerificationKey(ctx, kid, testApp)
+	})
+}
+
+func TestVerificationKey_ManagedRejectsAppSecretTokens(t *testing.T) {
+	ctx := context.Background()
+	k := newKeys(t, jwt.AlgRS256)
+
+	// Anyone holding the app secret can sign this token.
+	forged, err := jwt.NewToken(models.User{ID: 1, IsAdmin: true}, testApp, nil, time.Minute,
+		jwt.SigningKey{Algorithm: jwt.AlgHS256, Key: []byte(appSecret)},
+		jwt.Options{ClaimsVersion: jwt.ClaimsV1})
+	require.NoError(t, err)
+
+	_, err = parse(ctx, k, forged)
+	require.Error(t, err)
+
+	_, err = k.VerificationKey(ctx, "", testApp)
+	assert.ErrorIs(t, err, keys.ErrKeyNotFound)
+
+	key, err := k.SigningKey(ctx, testApp)
+	require.NoError(t, err)
+	assert.Equal(t, jwt.AlgRS256, key.Algorithm)
+	assert.NotEmpty(t, key.ID)
+
+	token, err := jwt.NewToken(models.User{ID: 1}, testApp, nil, time.Minute, key, jwt.Options{ClaimsVersion: jwt.ClaimsV1})
+	require.NoError(t, err)
+
+	claims, err := parse(ctx, k, token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UID)
+}
+
+func TestVerificationKey_HS256UsesAppSecrets(t *testing.T) {
+	ctx := context.Background()
+	k := newKeys(t, jwt.AlgHS256)
+
+	key, err := k.SigningKey(ctx, testApp)
+	require.NoError(t, err)
+	assert.Empty(t, key.ID)
+
+	token, err := jwt.NewToken(models.User{ID: 1}, testApp, nil, time.Minute, key, jwt.Options{ClaimsVersion: jwt.ClaimsV1})
+	require.NoError(t, err)
+
+	_, err = parse(ctx, k, token)
+	require.NoError(t, err)
+}
+
+func TestSigningKey_Managed(t *testing.T) {
+	for _, algorithm := range []string{jwt.AlgRS256, jwt.AlgES256, jwt.AlgEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			ctx := context.Background()
+			k := newKeys(t, algorithm)
+
+			key, err := k.SigningKey(ctx, testApp)
+			require.NoError(t, err)
+
+			token, err := jwt.NewToken(models.User{ID: 1}, testApp, nil, time.Minute, key, jwt.Options{ClaimsVersion: jwt.ClaimsV2})
+			require.NoError(t, err)
+
+			unverified, _, err := gojwt.NewParser().ParseUnverified(token, gojwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, unverified.Header["alg"])
+			assert.Equal(t, key.ID, unverified.Header["kid"])
+
+			set, err := k.JWKS(ctx)
+			require.NoError(t, err)
+			require.Len(t, set.Keys, 1)
+			assert.Equal(t, key.ID, set.Keys[0].Kid)
+			assert.Equal(t, algorithm, set.Keys[0].Alg)
+
+			_, err = parse(ctx, k, token)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRotate_KeyLifecycle(t *testing.T) {
+	const tokenTTL = 50 * time.Millisecond
+
+	ctx := context.Background()
+	k, st := newKeysWithStorage(t, jwt.AlgES256, tokenTTL)
+
+	states := func() map[string]string {
+		stored, err := st.SigningKeys(ctx)
+		require.NoError(t, err)
+
+		states := make(map[string]string, len(stored))
+		for _, key := range stored {
+			states[key.ID] = key.State
+		}
+		return states
+	}
+
+	published := func() []string {
+		set, err := k.JWKS(ctx)
+		require.NoError(t, err)
+
+		var kids []string
+		for _, key := range set.Keys {
+			kids = append(kids, key.Kid)
+		}
+		return kids
+	}
+
+	sign := func() (string, string) {
+		key, err := k.SigningKey(ctx, testApp)
+		require.NoError(t, err)
+
+		token, err := jwt.NewToken(models.User{ID: 1}, testApp, nil, time.Minute, key, jwt.Options{ClaimsVersion: jwt.ClaimsV2})
+		require.NoError(t, err)
+
+		return key.ID, token
+	}
+
+	// The first rotation activates a key and publishes the next one as
+	// pending before it signs anything.
+	require.NoError(t, k.Rotate(ctx, false))
+
+	first, firstToken := sign()
+	st1 := states()
+	require.Len(t, st1, 2)
+	assert.Equal(t, models.KeyStateActive, st1[first])
+
+	var pending string
+	for kid, state := range st1 {
+		if kid != first {
+			pending = kid
+			assert.Equal(t, models.KeyStatePending, state)
+		}
+	}
+	assert.ElementsMatch(t, []string{first, pending}, published())
+
+	// Not due yet: nothing changes.
+	require.NoError(t, k.Rotate(ctx, false))
+	assert.Equal(t, st1, states())
+
+	// Rotating activates the pending key and retires the active one, which
+	// stays published so that the tokens it signed still verify.
+	require.NoError(t, k.Rotate(ctx, true))
+
+	second, _ := sign()
+	assert.Equal(t, pending, second)
+
+	st2 := states()
+	require.Len(t, st2, 3)
+	assert.Equal(t, models.KeyStateRetiring, st2[first])
+	assert.Equal(t, models.KeyStateActive, st2[second])
+	assert.Contains(t, published(), first)
+
+	_, err := parse(ctx, k, firstToken)
+	require.NoError(t, err)
+
+	// Once tokenTTL has passed every token it signed has expired, so the
+	// retiring key is retired and no longer published.
+	time.Sleep(tokenTTL)
+	require.NoError(t, k.Rotate(ctx, false))
+
+	st3 := states()
+	assert.NotContains(t, st3, first)
+	assert.Equal(t, models.KeyStateActive, st3[second])
+	assert.NotContains(t, published(), first)
+
+	_, err = k.VerificationKey(ctx, first, testApp)
+	assert.ErrorIs(t, err, keys.ErrKeyNotFound)
+}
@@ -0,0 +1,74 @@
+package keys
+
+import (
+	"context"
+	"log/slog"
+	"sso/internal/lib/logger/sl"
+	"time"
+)
+
+// Rotator periodically calls Keys.Rotate so that keys are rotated on schedule
+// and retiring keys are retired once their last token has expired.
+type Rotator struct {
+	log           *slog.Logger
+	keys          *Keys
+	checkInterval time.Duration
+	stop          chan struct{}
+	done          chan struct{}
+}
+
+func NewRotator(log *slog.Logger, keys *Keys, checkInterval time.Duration) *Rotator {
+	return &Rotator{
+		log:           log,
+		keys:          keys,
+		checkInterval: checkInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Run checks the keys until Stop is called. It does nothing if tokens are
+// signed with app secrets.
+func (r *Rotator) Run() {
+	const op = "keys.Rotator.Run"
+
+	defer close(r.done)
+
+	if !r.keys.Managed() {
+		return
+	}
+
+	log := r.log.With(slog.String("op", op))
+	log.Info("starting signing key rotator", slog.Duration("check_interval", r.checkInterval))
+
+	ticker := time.NewTicker(r.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		r.rotate(log)
+
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Rotator) Stop() {
+	const op = "keys.Rotator.Stop"
+
+	r.log.With(slog.String("op", op)).Info("stopping signing key rotator")
+
+	close(r.stop)
+	<-r.done
+}
+
+func (r *Rotator) rotate(log *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.checkInterval)
+	defer cancel()
+
+	if err := r.keys.Rotate(ctx, false); err != nil {
+		log.Error("failed to rotate signing keys", sl.Err(err))
+	}
+}
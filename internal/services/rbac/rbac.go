@@ -0,0 +1,119 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrAppNotFound  = errors.New("app not found")
+	ErrRoleNotFound = errors.New("role not found")
+)
+
+// RBAC manages the roles users have in each app and the permissions those
+// roles grant. Roles and permissions are scoped to a single app; the global
+// users.is_admin flag only marks administrators of the SSO service itself.
+type RBAC struct {
+	log          *slog.Logger
+	roleProvider RoleProvider
+}
+
+type RoleProvider interface {
+	AssignRole(ctx context.Context, userID int64, appID int64, role string) error
+	RevokeRole(ctx context.Context, userID int64, appID int64, role string) error
+	GrantPermission(ctx context.Context, appID int64, role string, permission string) error
+	HasPermission(ctx context.Context, userID int64, appID int64, permission string) (bool, error)
+}
+
+func New(log *slog.Logger, roleProvider RoleProvider) *RBAC {
+	return &RBAC{log: log, roleProvider: roleProvider}
+}
+
+func (r *RBAC) AssignRole(ctx context.Context, userID int64, appID int64, role string) error {
+	const op = "rbac.AssignRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+		slog.Int64("app_id", appID),
+		slog.String("role", role),
+	)
+
+	if err := r.roleProvider.AssignRole(ctx, userID, appID, role); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		log.Error("failed to assign role", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("role assigned")
+
+	return nil
+}
+
+func (r *RBAC) RevokeRole(ctx context.Context, userID int64, appID int64, role string) error {
+	const op = "rbac.RevokeRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+		slog.Int64("app_id", appID),
+		slog.String("role", role),
+	)
+
+	if err := r.roleProvider.RevokeRole(ctx, userID, appID, role); err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrRoleNotFound)
+		}
+		log.Error("failed to revoke role", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("role revoked")
+
+	return nil
+}
+
+func (r *RBAC) GrantPermission(ctx context.Context, appID int64, role string, permission string) error {
+	const op = "rbac.GrantPermission"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int64("app_id", appID),
+		slog.String("role", role),
+		slog.String("permission", permission),
+	)
+
+	if err := r.roleProvider.GrantPermission(ctx, appID, role, permission); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		log.Error("failed to grant permission", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("permission granted")
+
+	return nil
+}
+
+// HasPermission reports whether any role userID has in appID grants permission.
+func (r *RBAC) HasPermission(ctx context.Context, userID int64, appID int64, permission string) (bool, error) {
+	const op = "rbac.HasPermission"
+
+	has, err := r.roleProvider.HasPermission(ctx, userID, appID, permission)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return has, nil
+}
@@ -0,0 +1,138 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ilyakaznacheev/cleanenv"
+	"os"
+	"sso/internal/domain/models"
+	"strings"
+	"time"
+)
+
+// File is the layout of a registry file:
+//
+//	apps:
+//	  - id: 1
+//	    name: billing
+//	    secret_ref: env:SSO_APP_BILLING_SECRET
+//	    token_ttl: 15m
+//	    scopes: [invoices:read, invoices:write]
+//	    issuer: https://sso.example.com
+//	    audience: billing
+//	    claims: [email, is_admin]
+//	    require_verified_email: true
+//
+// A secret_ref is either env:NAME, the environment variable NAME, or
+// file:PATH, the contents of the file at PATH without the trailing newline.
+// Secrets themselves do not belong in the file, so that it can be reviewed
+// and kept in git.
+//
+// claims lists the optional claims of the app's tokens; without it they
+// carry models.DefaultClaims, with an empty list none.
+type File struct {
+	Apps []AppSpec `yaml:"apps"`
+}
+
+type AppSpec struct {
+	ID                   int           `yaml:"id"`
+	Name                 string        `yaml:"name"`
+	SecretRef            string        `yaml:"secret_ref"`
+	TokenTTL             time.Duration `yaml:"token_ttl"`
+	Scopes               []string      `yaml:"scopes"`
+	Issuer               string        `yaml:"issuer"`
+	Audience             string        `yaml:"audience"`
+	Claims               []string      `yaml:"claims"`
+	RequireVerifiedEmail bool          `yaml:"require_verified_email"`
+}
+
+// Load reads and validates the registry file at path.
+func Load(path string) (File, error) {
+	const op = "registry.Load"
+
+	var file File
+	if err := cleanenv.ReadConfig(path, &file); err != nil {
+		return File{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := file.validate(); err != nil {
+		return File{}, fmt.Errorf("%s: %s: %w", op, path, err)
+	}
+
+	return file, nil
+}
+
+func (f File) validate() error {
+	ids := make(map[int]bool, len(f.Apps))
+	names := make(map[string]bool, len(f.Apps))
+
+	for i, app := range f.Apps {
+		switch {
+		case app.ID <= 0:
+			return fmt.Errorf("apps[%d]: id must be positive", i)
+		case app.Name == "":
+			return fmt.Errorf("app %d: name is required", app.ID)
+		case ids[app.ID]:
+			return fmt.Errorf("app %d: id is listed twice", app.ID)
+		case names[app.Name]:
+			return fmt.Errorf("app %d: name %q is listed twice", app.ID, app.Name)
+		case app.TokenTTL < 0 || app.TokenTTL%time.Second != 0:
+			return fmt.Errorf("app %d: token_ttl must be zero or a positive number of whole seconds", app.ID)
+		}
+
+		if _, _, ok := parseSecretRef(app.SecretRef); !ok {
+			return fmt.Errorf("app %d: secret_ref must be env:NAME or file:PATH", app.ID)
+		}
+
+		for _, scope := range app.Scopes {
+			if scope == "" || strings.ContainsAny(scope, " \t\n") {
+				return fmt.Errorf("app %d: invalid scope %q", app.ID, scope)
+			}
+		}
+
+		for _, claim := range app.Claims {
+			switch claim {
+			case models.ClaimEmail, models.ClaimIsAdmin, models.ClaimRoles:
+			default:
+				return fmt.Errorf("app %d: unknown claim %q", app.ID, claim)
+			}
+		}
+
+		ids[app.ID] = true
+		names[app.Name] = true
+	}
+
+	return nil
+}
+
+func parseSecretRef(ref string) (kind string, name string, ok bool) {
+	kind, name, ok = strings.Cut(ref, ":")
+	if !ok || name == "" || (kind != "env" && kind != "file") {
+		return "", "", false
+	}
+
+	return kind, name, true
+}
+
+// resolveSecret returns the secret ref points to.
+func resolveSecret(ref string) (string, error) {
+	kind, name, _ := parseSecretRef(ref)
+
+	var secret string
+	switch kind {
+	case "env":
+		secret = os.Getenv(name)
+	case "file":
+		raw, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		secret = strings.TrimRight(string(raw), "\r\n")
+	}
+
+	if secret == "" {
+		return "", errors.New("secret " + ref + " is empty")
+	}
+
+	return secret, nil
+}
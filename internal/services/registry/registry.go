@@ -0,0 +1,240 @@
+// Package registry reconciles the registered apps with a registry file, so
+// that apps can be managed through review like any other configuration.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"strings"
+	"time"
+)
+
+// Actions of a Change.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Fields an update can change.
+const (
+	FieldName     = "name"
+	FieldTokenTTL = "token_ttl"
+	FieldScopes   = "scopes"
+	FieldIssuer   = "issuer"
+	FieldAudience = "audience"
+	FieldClaims   = "claims"
+	FieldSecret   = "secret"
+
+	FieldRequireVerifiedEmail = "require_verified_email"
+)
+
+// settingsFields are the fields UpdateAppSettings stores.
+var settingsFields = []string{FieldTokenTTL, FieldScopes, FieldIssuer, FieldAudience, FieldClaims, FieldRequireVerifiedEmail}
+
+// Registry reconciles the stored apps with a File.
+type Registry struct {
+	log               *slog.Logger
+	appStorage        AppStorage
+	secretGracePeriod time.Duration
+}
+
+type AppStorage interface {
+	SaveApp(ctx context.Context, app models.App) error
+	App(ctx context.Context, appID int64) (models.App, error)
+	Apps(ctx context.Context) ([]models.App, error)
+	UpdateApp(ctx context.Context, app models.App) error
+	UpdateAppSettings(ctx context.Context, app models.App) error
+	DeleteApp(ctx context.Context, appID int64) error
+	RotateAppSecret(ctx context.Context, appID int64, secret string, retireAt time.Time) error
+}
+
+// New returns a Registry. When the file references a new secret for an app,
+// the previous one stays valid for secretGracePeriod.
+func New(log *slog.Logger, appStorage AppStorage, secretGracePeriod time.Duration) *Registry {
+	return &Registry{
+		log:               log,
+		appStorage:        appStorage,
+		secretGracePeriod: secretGracePeriod,
+	}
+}
+
+// Options control Reconcile. Apps that are stored but not listed in the file
+// are only deleted with Prune. With DryRun nothing is changed.
+type Options struct {
+	Prune  bool
+	DryRun bool
+}
+
+// Change is a difference between the file and the stored apps. App is the
+// app as the file describes it, or as stored for a delete. Fields lists what
+// an update changes.
+type Change struct {
+	Action string
+	App    models.App
+	Fields []string
+}
+
+// String describes the change in diff style without revealing secrets.
+func (c Change) String() string {
+	switch c.Action {
+	case ActionCreate:
+		return fmt.Sprintf("+ app %d %q", c.App.ID, c.App.Name)
+	case ActionDelete:
+		return fmt.Sprintf("- app %d %q", c.App.ID, c.App.Name)
+	default:
+		return fmt.Sprintf("~ app %d %q: %s", c.App.ID, c.App.Name, strings.Join(c.Fields, ", "))
+	}
+}
+
+// Reconcile makes the stored apps match file and returns the changes it
+// made, or would make with opts.DryRun. Deletes are applied first, so that
+// their names are free for the apps created or renamed after them.
+func (r *Registry) Reconcile(ctx context.Context, file File, opts Options) ([]Change, error) {
+	const op = "registry.Reconcile"
+
+	log := r.log.With(slog.String("op", op), slog.Bool("dry_run", opts.DryRun))
+
+	changes, err := r.plan(ctx, file, opts.Prune)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if opts.DryRun {
+		return changes, nil
+	}
+
+	for i, change := range changes {
+		if err := r.apply(ctx, change); err != nil {
+			log.Error("failed to apply change", slog.String("change", change.String()), sl.Err(err))
+			return changes[:i], fmt.Errorf("%s: %s: %w", op, change, err)
+		}
+
+		log.Info("app reconciled", slog.String("change", change.String()))
+	}
+
+	return changes, nil
+}
+
+func (r *Registry) plan(ctx context.Context, file File, prune bool) ([]Change, error) {
+	stored, err := r.appStorage.Apps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[int]models.App, len(stored))
+	for _, app := range stored {
+		current[app.ID] = app
+	}
+
+	var deletes, updates, creates []Change
+
+	for _, spec := range file.Apps {
+		secret, err := resolveSecret(spec.SecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("app %d: %w", spec.ID, err)
+		}
+
+		desired := models.App{
+			ID:                   spec.ID,
+			Name:                 spec.Name,
+			Secret:               secret,
+			TokenTTL:             spec.TokenTTL,
+			Scopes:               spec.Scopes,
+			Issuer:               spec.Issuer,
+			Audience:             spec.Audience,
+			Claims:               spec.Claims,
+			RequireVerifiedEmail: spec.RequireVerifiedEmail,
+		}
+
+		app, ok := current[spec.ID]
+		if !ok {
+			creates = append(creates, Change{Action: ActionCreate, App: desired})
+			continue
+		}
+		delete(current, spec.ID)
+
+		var fields []string
+		if app.Name != desired.Name {
+			fields = append(fields, FieldName)
+		}
+		if app.TokenTTL != desired.TokenTTL {
+			fields = append(fields, FieldTokenTTL)
+		}
+		if !slices.Equal(app.Scopes, desired.Scopes) {
+			fields = append(fields, FieldScopes)
+		}
+		if app.Issuer != desired.Issuer {
+			fields = append(fields, FieldIssuer)
+		}
+		if app.Audience != desired.Audience {
+			fields = append(fields, FieldAudience)
+		}
+		// Nil claims, the defaults, differ from an empty list.
+		if (app.Claims == nil) != (desired.Claims == nil) || !slices.Equal(app.Claims, desired.Claims) {
+			fields = append(fields, FieldClaims)
+		}
+		if app.RequireVerifiedEmail != desired.RequireVerifiedEmail {
+			fields = append(fields, FieldRequireVerifiedEmail)
+		}
+
+		// Apps does not load secrets.
+		withSecrets, err := r.appStorage.App(ctx, int64(spec.ID))
+		if err != nil {
+			return nil, fmt.Errorf("app %d: %w", spec.ID, err)
+		}
+		if withSecrets.Secret != desired.Secret {
+			fields = append(fields, FieldSecret)
+		}
+
+		if len(fields) > 0 {
+			updates = append(updates, Change{Action: ActionUpdate, App: desired, Fields: fields})
+		}
+	}
+
+	if prune {
+		for _, app := range stored {
+			if _, ok := current[app.ID]; ok {
+				deletes = append(deletes, Change{Action: ActionDelete, App: app})
+			}
+		}
+	}
+
+	return slices.Concat(deletes, updates, creates), nil
+}
+
+func (r *Registry) apply(ctx context.Context, change Change) error {
+	app := change.App
+
+	switch change.Action {
+	case ActionCreate:
+		return r.appStorage.SaveApp(ctx, app)
+	case ActionDelete:
+		return r.appStorage.DeleteApp(ctx, int64(app.ID))
+	}
+
+	if slices.Contains(change.Fields, FieldName) {
+		if err := r.appStorage.UpdateApp(ctx, app); err != nil {
+			return err
+		}
+	}
+
+	if slices.ContainsFunc(change.Fields, func(field string) bool { return slices.Contains(settingsFields, field) }) {
+		if err := r.appStorage.UpdateAppSettings(ctx, app); err != nil {
+			return err
+		}
+	}
+
+	if slices.Contains(change.Fields, FieldSecret) {
+		retireAt := time.Now().Add(r.secretGracePeriod)
+		if err := r.appStorage.RotateAppSecret(ctx, int64(app.ID), app.Secret, retireAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
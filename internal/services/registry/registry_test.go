@@ -0,0 +1,250 @@
+package registry_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"sso/internal/domain/models"
+	"sso/internal/services/registry"
+	"sso/internal/storage"
+	"testing"
+	"time"
+)
+
+const gracePeriod = time.Hour
+
+// fakeStorage keeps apps in a map and records the writes made to it.
+type fakeStorage struct {
+	apps     map[int]models.App
+	writes   []string
+	retireAt time.Time
+}
+
+func newFakeStorage(apps ...models.App) *fakeStorage {
+	s := &fakeStorage{apps: make(map[int]models.App)}
+	for _, app := range apps {
+		s.apps[app.ID] = app
+	}
+
+	return s
+}
+
+func (s *fakeStorage) SaveApp(_ context.Context, app models.App) error {
+	s.writes = append(s.writes, fmt.Sprintf("save %d", app.ID))
+	s.apps[app.ID] = app
+
+	return nil
+}
+
+func (s *fakeStorage) App(_ context.Context, appID int64) (models.App, error) {
+	app, ok := s.apps[int(appID)]
+	if !ok {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return app, nil
+}
+
+// Apps leaves out the secrets, like the real storages.
+func (s *fakeStorage) Apps(_ context.Context) ([]models.App, error) {
+	apps := make([]models.App, 0, len(s.apps))
+	for _, id := range slices.Sorted(maps.Keys(s.apps)) {
+		app := s.apps[id]
+		app.Secret = ""
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+func (s *fakeStorage) UpdateApp(_ context.Context, app models.App) error {
+	s.writes = append(s.writes, fmt.Sprintf("update %d", app.ID))
+
+	stored := s.apps[app.ID]
+	stored.Name = app.Name
+	s.apps[app.ID] = stored
+
+	return nil
+}
+
+func (s *fakeStorage) UpdateAppSettings(_ context.Context, app models.App) error {
+	s.writes = append(s.writes, fmt.Sprintf("update settings %d", app.ID))
+
+	stored := s.apps[app.ID]
+	stored.TokenTTL = app.TokenTTL
+	stored.Scopes = app.Scopes
+	stored.Issuer = app.Issuer
+	stored.Audience = app.Audience
+	stored.Claims = app.Claims
+	stored.RequireVerifiedEmail = app.RequireVerifiedEmail
+	s.apps[app.ID] = stored
+
+	return nil
+}
+
+func (s *fakeStorage) DeleteApp(_ context.Context, appID int64) error {
+	s.writes = append(s.writes, fmt.Sprintf("delete %d", appID))
+	delete(s.apps, int(appID))
+
+	return nil
+}
+
+func (s *fakeStorage) RotateAppSecret(_ context.Context, appID int64, secret string, retireAt time.Time) error {
+	s.writes = append(s.writes, fmt.Sprintf("rotate %d", appID))
+	s.retireAt = retireAt
+
+	stored := s.apps[int(appID)]
+	stored.Secret = secret
+	s.apps[int(appID)] = stored
+
+	return nil
+}
+
+func newRegistry(st *fakeStorage) *registry.Registry {
+	return registry.New(slog.New(slog.NewTextHandler(io.Discard, nil)), st, gracePeriod)
+}
+
+// stored returns the apps the reconcile tests start from.
+func stored() []models.App {
+	return []models.App{
+		{ID: 1, Name: "billing", Secret: "billing-secret", TokenTTL: time.Minute},
+		{ID: 2, Name: "legacy", Secret: "legacy-secret"},
+		{ID: 3, Name: "reports", Secret: "reports-secret", Scopes: []string{"reports:read"}},
+	}
+}
+
+// file lists app 1 renamed and with a new TTL, app 3 unchanged and a new app
+// 4; app 2 is missing.
+func file(t *testing.T) registry.File {
+	t.Setenv("BILLING_SECRET", "billing-secret")
+	t.Setenv("REPORTS_SECRET", "reports-secret")
+	t.Setenv("SEARCH_SECRET", "search-secret")
+
+	return registry.File{Apps: []registry.AppSpec{
+		{ID: 1, Name: "invoicing", SecretRef: "env:BILLING_SECRET", TokenTTL: 5 * time.Minute},
+		{ID: 3, Name: "reports", SecretRef: "env:REPORTS_SECRET", Scopes: []string{"reports:read"}},
+		{ID: 4, Name: "search", SecretRef: "env:SEARCH_SECRET", Claims: []string{}},
+	}}
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name       string
+		prune      bool
+		wantChange []string
+		wantWrites []string
+		wantApps   []int
+	}{
+		{
+			name: "keeps unlisted apps",
+			wantChange: []string{
+				`~ app 1 "invoicing": name, token_ttl`,
+				`+ app 4 "search"`,
+			},
+			wantWrites: []string{"update 1", "update settings 1", "save 4"},
+			wantApps:   []int{1, 2, 3, 4},
+		},
+		{
+			name:  "prune deletes unlisted apps first",
+			prune: true,
+			wantChange: []string{
+				`- app 2 "legacy"`,
+				`~ app 1 "invoicing": name, token_ttl`,
+				`+ app 4 "search"`,
+			},
+			wantWrites: []string{"delete 2", "update 1", "update settings 1", "save 4"},
+			wantApps:   []int{1, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newFakeStorage(stored()...)
+
+			changes, err := newRegistry(st).Reconcile(context.Background(), file(t), registry.Options{Prune: tt.prune})
+			require.NoError(t, err)
+
+			var described []string
+			for _, change := range changes {
+				described = append(described, change.String())
+			}
+			assert.Equal(t, tt.wantChange, described)
+			assert.Equal(t, tt.wantWrites, st.writes)
+			assert.Equal(t, tt.wantApps, slices.Sorted(maps.Keys(st.apps)))
+
+			assert.Equal(t, "invoicing", st.apps[1].Name)
+			assert.Equal(t, 5*time.Minute, st.apps[1].TokenTTL)
+			assert.Equal(t, "search-secret", st.apps[4].Secret)
+			assert.Equal(t, []string{}, st.apps[4].Claims)
+
+			// Once applied, the file and the storage agree.
+			changes, err = newRegistry(st).Reconcile(context.Background(), file(t), registry.Options{Prune: tt.prune})
+			require.NoError(t, err)
+			assert.Empty(t, changes)
+		})
+	}
+}
+
+func TestReconcile_DryRun(t *testing.T) {
+	st := newFakeStorage(stored()...)
+
+	changes, err := newRegistry(st).Reconcile(context.Background(), file(t), registry.Options{Prune: true, DryRun: true})
+	require.NoError(t, err)
+
+	assert.Len(t, changes, 3)
+	assert.Empty(t, st.writes)
+	assert.Equal(t, newFakeStorage(stored()...).apps, st.apps)
+}
+
+func TestReconcile_RotatesSecretWhenRefChanges(t *testing.T) {
+	st := newFakeStorage(stored()...)
+
+	t.Setenv("REPORTS_SECRET_V2", "reports-secret-v2")
+
+	spec := file(t).Apps[1]
+	spec.SecretRef = "env:REPORTS_SECRET_V2"
+
+	before := time.Now()
+
+	changes, err := newRegistry(st).Reconcile(context.Background(), registry.File{Apps: []registry.AppSpec{spec}}, registry.Options{})
+	require.NoError(t, err)
+
+	require.Len(t, changes, 1)
+	assert.Equal(t, registry.ActionUpdate, changes[0].Action)
+	assert.Equal(t, []string{registry.FieldSecret}, changes[0].Fields)
+	assert.NotContains(t, changes[0].String(), "reports-secret")
+
+	// Only the secret changed, so neither the name nor the settings are
+	// written.
+	assert.Equal(t, []string{"rotate 3"}, st.writes)
+	assert.Equal(t, "reports-secret-v2", st.apps[3].Secret)
+	assert.WithinRange(t, st.retireAt, before.Add(gracePeriod), time.Now().Add(gracePeriod))
+}
+
+func TestReconcile_EmptySecret(t *testing.T) {
+	st := newFakeStorage(stored()...)
+
+	t.Setenv("BILLING_SECRET", "")
+
+	_, err := newRegistry(st).Reconcile(context.Background(), registry.File{Apps: []registry.AppSpec{
+		{ID: 1, Name: "billing", SecretRef: "env:BILLING_SECRET"},
+	}}, registry.Options{})
+	require.ErrorContains(t, err, "app 1: secret env:BILLING_SECRET is empty")
+	assert.Empty(t, st.writes)
+}
+
+// TestLoad_TestApps checks the apps file the memory test configs register.
+func TestLoad_TestApps(t *testing.T) {
+	f, err := registry.Load("../../../tests/config/apps.yaml")
+	require.NoError(t, err)
+
+	require.Len(t, f.Apps, 3)
+	assert.True(t, f.Apps[1].RequireVerifiedEmail)
+	assert.Equal(t, 5*time.Minute, f.Apps[2].TokenTTL)
+	assert.Equal(t, []string{"reports:read", "reports:write"}, f.Apps[2].Scopes)
+}
@@ -0,0 +1,263 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+// app is an app together with all of its secrets, oldest first.
+type app struct {
+	models.App
+	secrets []models.AppSecret
+}
+
+// SaveApp creates app with app.Secret as its active secret.
+// storage.ErrAppExists is returned if its ID or name is already taken.
+func (s *Storage) SaveApp(_ context.Context, newApp models.App) error {
+	const op = "storage.memory.SaveApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apps[int64(newApp.ID)]; ok || s.nameTaken(newApp.Name, newApp.ID) {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+	}
+
+	s.apps[int64(newApp.ID)] = app{
+		App: models.App{
+			ID:                   newApp.ID,
+			Name:                 newApp.Name,
+			TokenTTL:             newApp.TokenTTL,
+			Scopes:               slices.Clone(newApp.Scopes),
+			Issuer:               newApp.Issuer,
+			Audience:             newApp.Audience,
+			Claims:               slices.Clone(newApp.Claims),
+			RequireVerifiedEmail: newApp.RequireVerifiedEmail,
+		},
+		secrets: []models.AppSecret{{
+			Secret:    newApp.Secret,
+			State:     models.AppSecretActive,
+			CreatedAt: time.Now(),
+		}},
+	}
+
+	return nil
+}
+
+// App returns the app with its secrets that have not expired, newest first.
+func (s *Storage) App(_ context.Context, appID int64) (models.App, error) {
+	const op = "storage.memory.App"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.apps[appID]
+	if !ok {
+		return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	result := stored.App
+	now := time.Now()
+
+	for i := len(stored.secrets) - 1; i >= 0; i-- {
+		secret := stored.secrets[i]
+		if !secret.ExpiresAt.IsZero() && !secret.ExpiresAt.After(now) {
+			continue
+		}
+
+		result.Secrets = append(result.Secrets, secret)
+		if result.Secret == "" && secret.State == models.AppSecretActive {
+			result.Secret = secret.Secret
+		}
+	}
+
+	return result, nil
+}
+
+// Apps returns all apps ordered by ID. Secrets are not returned.
+func (s *Storage) Apps(_ context.Context) ([]models.App, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	apps := make([]models.App, 0, len(s.apps))
+	for _, stored := range s.apps {
+		apps = append(apps, stored.App)
+	}
+
+	sort.Slice(apps, func(i, j int) bool { return apps[i].ID < apps[j].ID })
+
+	return apps, nil
+}
+
+func (s *Storage) UpdateApp(_ context.Context, updated models.App) error {
+	const op = "storage.memory.UpdateApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.apps[int64(updated.ID)]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	if s.nameTaken(updated.Name, updated.ID) {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+	}
+
+	stored.Name = updated.Name
+	s.apps[int64(updated.ID)] = stored
+
+	return nil
+}
+
+// UpdateAppSettings stores the token TTL, scopes, issuer, audience, claims
+// and email verification requirement of app.
+func (s *Storage) UpdateAppSettings(_ context.Context, updated models.App) error {
+	const op = "storage.memory.UpdateAppSettings"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.apps[int64(updated.ID)]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	stored.TokenTTL = updated.TokenTTL
+	stored.Scopes = slices.Clone(updated.Scopes)
+	stored.Issuer = updated.Issuer
+	stored.Audience = updated.Audience
+	stored.Claims = slices.Clone(updated.Claims)
+	stored.RequireVerifiedEmail = updated.RequireVerifiedEmail
+	s.apps[int64(updated.ID)] = stored
+
+	return nil
+}
+
+// DeleteApp removes appID together with its roles and refresh tokens.
+func (s *Storage) DeleteApp(_ context.Context, appID int64) error {
+	const op = "storage.memory.DeleteApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apps[appID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	delete(s.apps, appID)
+
+	for hash, challenge := range s.mfaChallenges {
+		if challenge.AppID == appID {
+			delete(s.mfaChallenges, hash)
+		}
+	}
+
+	for hash, ceremony := range s.ceremonies {
+		if ceremony.AppID == appID {
+			delete(s.ceremonies, hash)
+		}
+	}
+
+	for key := range s.roles {
+		if key.appID == appID {
+			delete(s.roles, key)
+		}
+	}
+
+	for _, roles := range s.userRoles {
+		for key := range roles {
+			if key.appID == appID {
+				delete(roles, key)
+			}
+		}
+	}
+
+	for id, token := range s.refreshTokens {
+		if int64(token.AppID) == appID {
+			delete(s.refreshTokens, id)
+			delete(s.refreshTokenHashes, string(token.TokenHash))
+		}
+	}
+
+	return nil
+}
+
+// RotateAppSecret makes secret the active secret of app. Secrets that were
+// active become verify-only and expire at retireAt.
+func (s *Storage) RotateAppSecret(_ context.Context, appID int64, secret string, retireAt time.Time) error {
+	const op = "storage.memory.RotateAppSecret"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	for i := range stored.secrets {
+		if stored.secrets[i].State == models.AppSecretActive {
+			stored.secrets[i].State = models.AppSecretVerifyOnly
+			stored.secrets[i].ExpiresAt = retireAt
+		}
+	}
+
+	stored.secrets = append(stored.secrets, models.AppSecret{
+		Secret:    secret,
+		State:     models.AppSecretActive,
+		CreatedAt: time.Now(),
+	})
+	s.apps[appID] = stored
+
+	return nil
+}
+
+// RetireAppSecrets deletes the verify-only secrets of app, as well as any
+// expired ones, and returns how many were deleted. storage.ErrAppNotFound is
+// returned if there is no such app.
+func (s *Storage) RetireAppSecrets(_ context.Context, appID int64) (int64, error) {
+	const op = "storage.memory.RetireAppSecrets"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.apps[appID]
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	now := time.Now()
+	kept := stored.secrets[:0]
+
+	for _, secret := range stored.secrets {
+		expired := !secret.ExpiresAt.IsZero() && !secret.ExpiresAt.After(now)
+		if secret.State == models.AppSecretVerifyOnly || expired {
+			continue
+		}
+		kept = append(kept, secret)
+	}
+
+	retired := int64(len(stored.secrets) - len(kept))
+	stored.secrets = kept
+	s.apps[appID] = stored
+
+	return retired, nil
+}
+
+// nameTaken reports whether an app other than exceptID is called name.
+// s.mu must be held.
+func (s *Storage) nameTaken(name string, exceptID int) bool {
+	for _, stored := range s.apps {
+		if stored.Name == name && stored.ID != exceptID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+	"sso/internal/domain/models"
+)
+
+// AuditEvents returns the audit events of userID, oldest first.
+func (s *Storage) AuditEvents(_ context.Context, userID int64) ([]models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []models.AuditEvent
+	for _, event := range s.auditEvents {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,136 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"sso/internal/storage/memory"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStorage_ConcurrentAccess uses the storage from many goroutines at once,
+// as the servers do. It is meant to be run with -race.
+func TestStorage_ConcurrentAccess(t *testing.T) {
+	const workers = 16
+
+	ctx := context.Background()
+	st := memory.New()
+
+	require.NoError(t, st.SaveApp(ctx, models.App{ID: 1, Name: "test", Secret: "secret", Scopes: []string{"read"}}))
+	require.NoError(t, st.GrantPermission(ctx, 1, "editor", "articles:write"))
+
+	var wg sync.WaitGroup
+
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			email := fmt.Sprintf("user-%d@example.com", i)
+
+			userID, err := st.SaveUser(ctx, email, []byte("hash"))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			_, err = st.User(ctx, email)
+			assert.NoError(t, err)
+			assert.NoError(t, st.SetAdmin(ctx, userID, i%2 == 0))
+			assert.NoError(t, st.SetUserDisabled(ctx, userID, i%3 == 0))
+			_, err = st.UserByID(ctx, userID)
+			assert.NoError(t, err)
+			_, err = st.Users(ctx, 0, workers)
+			assert.NoError(t, err)
+
+			assert.NoError(t, st.AssignRole(ctx, userID, 1, "editor"))
+			has, err := st.HasPermission(ctx, userID, 1, "articles:write")
+			assert.NoError(t, err)
+			assert.True(t, has)
+			_, err = st.UserRoles(ctx, userID, 1)
+			assert.NoError(t, err)
+
+			now := time.Now()
+			hash := []byte(email)
+
+			assert.NoError(t, st.SaveSession(ctx, models.Session{TokenHash: hash, UserID: userID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+			session, err := st.Session(ctx, hash)
+			if assert.NoError(t, err) {
+				assert.NoError(t, st.TouchSession(ctx, session.ID, now.Add(-time.Minute)))
+			}
+
+			assert.NoError(t, st.SaveRefreshToken(ctx, models.RefreshToken{TokenHash: hash, FamilyID: email, UserID: userID, AppID: 1, ExpiresAt: now.Add(time.Hour)}))
+			_, err = st.RefreshToken(ctx, hash)
+			assert.NoError(t, err)
+
+			assert.NoError(t, st.RevokeToken(ctx, email, now.Add(time.Hour)))
+			revoked, err := st.IsTokenRevoked(ctx, email)
+			assert.NoError(t, err)
+			assert.True(t, revoked)
+
+			assert.NoError(t, st.RotateAppSecret(ctx, 1, fmt.Sprintf("secret-%d", i), now.Add(time.Hour)))
+			_, err = st.App(ctx, 1)
+			assert.NoError(t, err)
+			_, err = st.Apps(ctx)
+			assert.NoError(t, err)
+
+			if i%2 == 1 {
+				assert.NoError(t, st.DeleteUser(ctx, userID))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	users, err := st.Users(ctx, 0, workers)
+	require.NoError(t, err)
+	assert.Len(t, users, workers/2)
+}
+
+func TestStorage_AppSecrets_UnknownApp(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+
+	err := st.RotateAppSecret(ctx, 1, "secret", time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, storage.ErrAppNotFound)
+
+	_, err = st.RetireAppSecrets(ctx, 1)
+	assert.ErrorIs(t, err, storage.ErrAppNotFound)
+}
+
+func TestLoadSeed(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "seed.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`users:
+  - email: admin@example.com
+    pass_hash: admin-hash
+    is_admin: true
+    email_verified: true
+  - email: user@example.com
+    pass_hash: user-hash
+`), 0o600))
+
+	st := memory.New()
+	require.NoError(t, st.LoadSeed(path))
+
+	admin, err := st.User(ctx, "admin@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("admin-hash"), admin.PassHash)
+	assert.True(t, admin.IsAdmin)
+	assert.True(t, admin.EmailVerified)
+
+	user, err := st.User(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.False(t, user.IsAdmin)
+	assert.False(t, user.EmailVerified)
+
+	// Seeding the same users twice is refused rather than silently merged.
+	assert.Error(t, st.LoadSeed(path))
+}
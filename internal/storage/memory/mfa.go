@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+// SaveTOTP stores the unconfirmed authenticator of a user, replacing one
+// that was never confirmed. storage.ErrTOTPConfirmed is returned if the user
+// already has a confirmed authenticator.
+func (s *Storage) SaveTOTP(_ context.Context, totp models.TOTP) error {
+	const op = "storage.memory.SaveTOTP"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.totps[totp.UserID]; ok && !current.ConfirmedAt.IsZero() {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPConfirmed)
+	}
+
+	totp.ConfirmedAt = time.Time{}
+	totp.LastStep = 0
+	totp.Failures = 0
+	totp.LastFailureAt = time.Time{}
+	s.totps[totp.UserID] = totp
+
+	return nil
+}
+
+func (s *Storage) TOTP(_ context.Context, userID int64) (models.TOTP, error) {
+	const op = "storage.memory.TOTP"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	totp, ok := s.totps[userID]
+	if !ok {
+		return models.TOTP{}, fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+	}
+
+	return totp, nil
+}
+
+// ConfirmTOTP marks the authenticator of userID as confirmed with a code of
+// step and replaces the user's recovery codes with recoveryCodeHashes.
+// storage.ErrTOTPNotFound is returned if the user has no unconfirmed
+// authenticator.
+func (s *Storage) ConfirmTOTP(_ context.Context, userID int64, step int64, recoveryCodeHashes [][]byte) error {
+	const op = "storage.memory.ConfirmTOTP"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totp, ok := s.totps[userID]
+	if !ok || !totp.ConfirmedAt.IsZero() {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+	}
+
+	totp.ConfirmedAt = time.Now()
+	totp.LastStep = step
+	s.totps[userID] = totp
+
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[string(hash)] = false
+	}
+	s.recoveryCodes[userID] = codes
+
+	return nil
+}
+
+// UseTOTPStep records that a code of step was accepted for userID.
+// storage.ErrTOTPStepUsed is returned if a code of step or a later one was
+// accepted before.
+func (s *Storage) UseTOTPStep(_ context.Context, userID int64, step int64) error {
+	const op = "storage.memory.UseTOTPStep"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totp, ok := s.totps[userID]
+	if !ok || totp.LastStep >= step {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPStepUsed)
+	}
+
+	totp.LastStep = step
+	s.totps[userID] = totp
+
+	return nil
+}
+
+// FailTOTP records that a wrong second factor was given for userID at at.
+// storage.ErrTOTPNotFound is returned if the user has no authenticator.
+func (s *Storage) FailTOTP(_ context.Context, userID int64, at time.Time) error {
+	const op = "storage.memory.FailTOTP"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totp, ok := s.totps[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+	}
+
+	totp.Failures++
+	totp.LastFailureAt = at
+	s.totps[userID] = totp
+
+	return nil
+}
+
+// ResetTOTPFailures forgets the wrong second factors given for userID.
+func (s *Storage) ResetTOTPFailures(_ context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if totp, ok := s.totps[userID]; ok {
+		totp.Failures = 0
+		s.totps[userID] = totp
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks a recovery code of userID as used.
+// storage.ErrRecoveryCodeNotFound is returned if there is no such code or it
+// was used before.
+func (s *Storage) UseRecoveryCode(_ context.Context, userID int64, codeHash []byte) error {
+	const op = "storage.memory.UseRecoveryCode"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used, ok := s.recoveryCodes[userID][string(codeHash)]
+	if !ok || used {
+		return fmt.Errorf("%s: %w", op, storage.ErrRecoveryCodeNotFound)
+	}
+
+	s.recoveryCodes[userID][string(codeHash)] = true
+
+	return nil
+}
+
+func (s *Storage) SaveMFAChallenge(_ context.Context, challenge models.MFAChallenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge.Attempts = 0
+	s.mfaChallenges[string(challenge.TokenHash)] = challenge
+
+	return nil
+}
+
+func (s *Storage) MFAChallenge(_ context.Context, tokenHash []byte) (models.MFAChallenge, error) {
+	const op = "storage.memory.MFAChallenge"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	challenge, ok := s.mfaChallenges[string(tokenHash)]
+	if !ok {
+		return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, storage.ErrMFAChallengeNotFound)
+	}
+
+	return challenge, nil
+}
+
+// FailMFAChallenge records a wrong code presented for the challenge.
+func (s *Storage) FailMFAChallenge(_ context.Context, tokenHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if challenge, ok := s.mfaChallenges[string(tokenHash)]; ok {
+		challenge.Attempts++
+		s.mfaChallenges[string(tokenHash)] = challenge
+	}
+
+	return nil
+}
+
+// DeleteMFAChallenge removes a completed challenge.
+// storage.ErrMFAChallengeNotFound is returned if it was already removed,
+// which means it was completed concurrently.
+func (s *Storage) DeleteMFAChallenge(_ context.Context, tokenHash []byte) error {
+	const op = "storage.memory.DeleteMFAChallenge"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.mfaChallenges[string(tokenHash)]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrMFAChallengeNotFound)
+	}
+
+	delete(s.mfaChallenges, string(tokenHash))
+
+	return nil
+}
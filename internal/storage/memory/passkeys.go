@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+// SavePasskey stores a passkey registered by a user. storage.ErrPasskeyExists
+// is returned if the credential is already registered.
+func (s *Storage) SavePasskey(_ context.Context, passkey models.Passkey) error {
+	const op = "storage.memory.SavePasskey"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, current := range s.passkeys {
+		if bytes.Equal(current.CredentialID, passkey.CredentialID) {
+			return fmt.Errorf("%s: %w", op, storage.ErrPasskeyExists)
+		}
+	}
+
+	s.lastPasskeyID++
+	passkey.ID = s.lastPasskeyID
+	passkey.LastUsedAt = time.Time{}
+	s.passkeys[passkey.ID] = passkey
+
+	return nil
+}
+
+// Passkeys returns the passkeys of userID, oldest first.
+func (s *Storage) Passkeys(_ context.Context, userID int64) ([]models.Passkey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var passkeys []models.Passkey
+	for _, passkey := range s.passkeys {
+		if passkey.UserID == userID {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+
+	sort.Slice(passkeys, func(i, j int) bool { return passkeys[i].ID < passkeys[j].ID })
+
+	return passkeys, nil
+}
+
+// UsePasskey records a login with the passkey and the signature counter the
+// authenticator reported. storage.ErrPasskeySignCount is returned unless the
+// counter increased or the authenticator does not keep one, which hints at a
+// cloned authenticator or a concurrent login with the same response.
+func (s *Storage) UsePasskey(_ context.Context, passkeyID int64, signCount uint32) error {
+	const op = "storage.memory.UsePasskey"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	passkey, ok := s.passkeys[passkeyID]
+	if !ok || !(passkey.SignCount < signCount || passkey.SignCount == 0 && signCount == 0) {
+		return fmt.Errorf("%s: %w", op, storage.ErrPasskeySignCount)
+	}
+
+	passkey.SignCount = signCount
+	passkey.LastUsedAt = time.Now()
+	s.passkeys[passkeyID] = passkey
+
+	return nil
+}
+
+func (s *Storage) SaveCeremony(_ context.Context, ceremony models.WebAuthnCeremony) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ceremonies[string(ceremony.TokenHash)] = ceremony
+
+	return nil
+}
+
+// TakeCeremony removes the ceremony and returns it, so that the response of
+// an authenticator can only be verified once. storage.ErrCeremonyNotFound is
+// returned if there is no such ceremony.
+func (s *Storage) TakeCeremony(_ context.Context, tokenHash []byte) (models.WebAuthnCeremony, error) {
+	const op = "storage.memory.TakeCeremony"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ceremony, ok := s.ceremonies[string(tokenHash)]
+	if !ok {
+		return models.WebAuthnCeremony{}, fmt.Errorf("%s: %w", op, storage.ErrCeremonyNotFound)
+	}
+	delete(s.ceremonies, string(tokenHash))
+
+	return ceremony, nil
+}
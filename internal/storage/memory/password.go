@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+// ChangePassword replaces the password hash of the user, discards the
+// password resets still pending for them, ends their SSO sessions other than
+// change.KeepSessionID, revokes their refresh tokens and records change.Event,
+// all at once.
+func (s *Storage) ChangePassword(_ context.Context, change models.PasswordChange) error {
+	const op = "storage.memory.ChangePassword"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[change.UserID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	user.PassHash = change.PassHash
+	s.users[change.UserID] = user
+
+	for hash, reset := range s.passwordResets {
+		if reset.UserID == change.UserID {
+			delete(s.passwordResets, hash)
+		}
+	}
+
+	s.revokeSessions(change.UserID, change.KeepSessionID)
+
+	s.lastAuditEventID++
+	event := change.Event
+	event.ID = s.lastAuditEventID
+	event.UserID = change.UserID
+	s.auditEvents = append(s.auditEvents, event)
+
+	return nil
+}
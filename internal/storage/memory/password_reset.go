@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+func (s *Storage) SavePasswordReset(_ context.Context, reset models.PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.passwordResets[string(reset.TokenHash)] = reset
+
+	return nil
+}
+
+// TakePasswordReset removes the reset and returns it, so that its token can
+// only be used once. storage.ErrPasswordResetNotFound is returned if there is
+// no such reset.
+func (s *Storage) TakePasswordReset(_ context.Context, tokenHash []byte) (models.PasswordReset, error) {
+	const op = "storage.memory.TakePasswordReset"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reset, ok := s.passwordResets[string(tokenHash)]
+	if !ok {
+		return models.PasswordReset{}, fmt.Errorf("%s: %w", op, storage.ErrPasswordResetNotFound)
+	}
+	delete(s.passwordResets, string(tokenHash))
+
+	return reset, nil
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+func (s *Storage) SaveRefreshToken(_ context.Context, token models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saveRefreshToken(token)
+
+	return nil
+}
+
+func (s *Storage) RefreshToken(_ context.Context, tokenHash []byte) (models.RefreshToken, error) {
+	const op = "storage.memory.RefreshToken"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.refreshTokenHashes[string(tokenHash)]
+	if !ok {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+	}
+
+	return s.refreshTokens[id], nil
+}
+
+// RotateRefreshToken marks oldID as rotated and saves next in its place.
+// storage.ErrRefreshTokenNotRotatable is returned if oldID has already been
+// rotated or revoked.
+func (s *Storage) RotateRefreshToken(_ context.Context, oldID int64, next models.RefreshToken) error {
+	const op = "storage.memory.RotateRefreshToken"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.refreshTokens[oldID]
+	if !ok || old.Rotated || old.Revoked {
+		return fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotRotatable)
+	}
+
+	old.Rotated = true
+	s.refreshTokens[oldID] = old
+
+	s.saveRefreshToken(next)
+
+	return nil
+}
+
+func (s *Storage) RevokeRefreshTokenFamily(_ context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.refreshTokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			s.refreshTokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+// saveRefreshToken stores token under a new ID. s.mu must be held.
+func (s *Storage) saveRefreshToken(token models.RefreshToken) {
+	s.lastRefreshTokenID++
+
+	token.ID = s.lastRefreshTokenID
+	token.Rotated, token.Revoked = false, false
+
+	s.refreshTokens[token.ID] = token
+	s.refreshTokenHashes[string(token.TokenHash)] = token.ID
+}
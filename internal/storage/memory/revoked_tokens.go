@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"time"
+)
+
+// RevokeToken adds jti to the denylist until expiresAt. Entries whose token
+// has already expired are purged on the way.
+func (s *Storage) RevokeToken(_ context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for revoked, until := range s.revokedTokens {
+		if until.Before(now) {
+			delete(s.revokedTokens, revoked)
+		}
+	}
+
+	if _, ok := s.revokedTokens[jti]; !ok {
+		s.revokedTokens[jti] = expiresAt
+	}
+
+	return nil
+}
+
+func (s *Storage) IsTokenRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	until, ok := s.revokedTokens[jti]
+
+	return ok && !until.Before(time.Now()), nil
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sso/internal/storage"
+)
+
+// AssignRole gives userID the role in appID, creating the role if needed.
+func (s *Storage) AssignRole(_ context.Context, userID int64, appID int64, role string) error {
+	const op = "storage.memory.AssignRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	key, err := s.upsertRole(appID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if s.userRoles[userID] == nil {
+		s.userRoles[userID] = make(map[roleKey]struct{})
+	}
+	s.userRoles[userID][key] = struct{}{}
+
+	return nil
+}
+
+func (s *Storage) RevokeRole(_ context.Context, userID int64, appID int64, role string) error {
+	const op = "storage.memory.RevokeRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := roleKey{appID: appID, name: role}
+
+	if _, ok := s.userRoles[userID][key]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	delete(s.userRoles[userID], key)
+
+	return nil
+}
+
+// GrantPermission adds permission to role in appID, creating the role if
+// needed.
+func (s *Storage) GrantPermission(_ context.Context, appID int64, role string, permission string) error {
+	const op = "storage.memory.GrantPermission"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.upsertRole(appID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.roles[key][permission] = struct{}{}
+
+	return nil
+}
+
+func (s *Storage) HasPermission(_ context.Context, userID int64, appID int64, permission string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key := range s.userRoles[userID] {
+		if key.appID != appID {
+			continue
+		}
+		if _, ok := s.roles[key][permission]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// UserRoles returns the names of the roles userID has in appID.
+func (s *Storage) UserRoles(_ context.Context, userID int64, appID int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var roles []string
+	for key := range s.userRoles[userID] {
+		if key.appID == appID {
+			roles = append(roles, key.name)
+		}
+	}
+
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+// upsertRole creates role in appID if it does not exist. s.mu must be held.
+func (s *Storage) upsertRole(appID int64, role string) (roleKey, error) {
+	if _, ok := s.apps[appID]; !ok {
+		return roleKey{}, storage.ErrAppNotFound
+	}
+
+	key := roleKey{appID: appID, name: role}
+	if _, ok := s.roles[key]; !ok {
+		s.roles[key] = make(map[string]struct{})
+	}
+
+	return key, nil
+}
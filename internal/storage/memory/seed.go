@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/ilyakaznacheev/cleanenv"
+	"sso/internal/domain/models"
+)
+
+// seed is the layout of a seed file:
+//
+//	users:
+//	  - email: admin@example.com
+//	    pass_hash: $2a$10$...
+//	    is_admin: true
+//	    email_verified: true
+//
+// Apps are not seeded: they are declared in the registry file, which the
+// server applies to every storage driver.
+type seed struct {
+	Users []struct {
+		Email         string `yaml:"email"`
+		PassHash      string `yaml:"pass_hash"`
+		IsAdmin       bool   `yaml:"is_admin"`
+		EmailVerified bool   `yaml:"email_verified"`
+	} `yaml:"users"`
+}
+
+// LoadSeed registers the users listed in the YAML file at path.
+func (s *Storage) LoadSeed(path string) error {
+	const op = "storage.memory.LoadSeed"
+
+	var parsed seed
+	if err := cleanenv.ReadConfig(path, &parsed); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, user := range parsed.Users {
+		id, err := s.SaveUser(context.Background(), user.Email, []byte(user.PassHash))
+		if err != nil {
+			return fmt.Errorf("%s: user %s: %w", op, user.Email, err)
+		}
+
+		err = s.updateUser(op, id, func(seeded *models.User) {
+			seeded.IsAdmin = user.IsAdmin
+			seeded.EmailVerified = user.EmailVerified
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+func (s *Storage) SaveSession(_ context.Context, session models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSessionID++
+
+	session.ID = s.lastSessionID
+	session.LastSeenAt = session.CreatedAt
+	session.Revoked = false
+
+	s.sessions[session.ID] = session
+	s.sessionHashes[string(session.TokenHash)] = session.ID
+
+	return nil
+}
+
+func (s *Storage) Session(_ context.Context, tokenHash []byte) (models.Session, error) {
+	const op = "storage.memory.Session"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.sessionHashes[string(tokenHash)]
+	if !ok {
+		return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+
+	return s.sessions[id], nil
+}
+
+// TouchSession records that sessionID was just used. storage.ErrSessionNotFound
+// is returned if the session is revoked, expired or has not been used since
+// idleSince.
+func (s *Storage) TouchSession(_ context.Context, sessionID int64, idleSince time.Time) error {
+	const op = "storage.memory.TouchSession"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	session, ok := s.sessions[sessionID]
+	if !ok || session.Revoked || !session.ExpiresAt.After(now) || !session.LastSeenAt.After(idleSince) {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+
+	session.LastSeenAt = now
+	s.sessions[sessionID] = session
+
+	return nil
+}
+
+func (s *Storage) RevokeSession(_ context.Context, sessionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[sessionID]; ok {
+		session.Revoked = true
+		s.sessions[sessionID] = session
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+func (s *Storage) SaveSigningKey(_ context.Context, key models.SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.signingKeys[key.ID] = key
+
+	return nil
+}
+
+// SigningKeys returns all keys that are not retired, oldest first.
+func (s *Storage) SigningKeys(_ context.Context) ([]models.SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []models.SigningKey
+	for _, key := range s.signingKeys {
+		if key.State != models.KeyStateRetired {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	return keys, nil
+}
+
+// RotateSigningKeys activates the pending key promote, moves every other
+// active key to retiring until retireAt and saves next as the new pending key.
+// storage.ErrSigningKeyNotFound is returned if promote is no longer pending.
+func (s *Storage) RotateSigningKeys(_ context.Context, promote string, retireAt time.Time, next models.SigningKey) error {
+	const op = "storage.memory.RotateSigningKeys"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promoted, ok := s.signingKeys[promote]
+	if !ok || promoted.State != models.KeyStatePending {
+		return fmt.Errorf("%s: %w", op, storage.ErrSigningKeyNotFound)
+	}
+
+	for kid, key := range s.signingKeys {
+		if key.State == models.KeyStateActive {
+			key.State = models.KeyStateRetiring
+			key.RetireAt = retireAt
+			s.signingKeys[kid] = key
+		}
+	}
+
+	promoted.State = models.KeyStateActive
+	promoted.ActivatedAt = time.Now()
+	s.signingKeys[promote] = promoted
+
+	next.State = models.KeyStatePending
+	s.signingKeys[next.ID] = next
+
+	return nil
+}
+
+// RetireSigningKeys moves retiring keys whose retire time has passed to
+// retired.
+func (s *Storage) RetireSigningKeys(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var retired int64
+	now := time.Now()
+
+	for kid, key := range s.signingKeys {
+		if key.State == models.KeyStateRetiring && !key.RetireAt.After(now) {
+			key.State = models.KeyStateRetired
+			s.signingKeys[kid] = key
+			retired++
+		}
+	}
+
+	return retired, nil
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+func (s *Storage) SaveUser(_ context.Context, email string, passHash []byte) (int64, error) {
+	const op = "storage.memory.SaveUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.userEmails[email]; ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	}
+
+	s.lastUserID++
+	s.users[s.lastUserID] = models.User{ID: s.lastUserID, Email: email, PassHash: passHash}
+	s.userEmails[email] = s.lastUserID
+
+	return s.lastUserID, nil
+}
+
+func (s *Storage) User(_ context.Context, email string) (models.User, error) {
+	const op = "storage.memory.User"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.userEmails[email]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return s.users[id], nil
+}
+
+func (s *Storage) UserByID(_ context.Context, userID int64) (models.User, error) {
+	const op = "storage.memory.UserByID"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) IsAdmin(_ context.Context, userID int64) (bool, error) {
+	const op = "storage.memory.IsAdmin"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return user.IsAdmin, nil
+}
+
+// Users returns up to limit users with IDs greater than afterID, ordered by
+// ID. Password hashes are not returned.
+func (s *Storage) Users(_ context.Context, afterID int64, limit int) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []models.User
+	for id, user := range s.users {
+		if id > afterID {
+			user.PassHash = nil
+			users = append(users, user)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	if len(users) > limit {
+		users = users[:limit]
+	}
+
+	return users, nil
+}
+
+func (s *Storage) SetAdmin(_ context.Context, userID int64, isAdmin bool) error {
+	const op = "storage.memory.SetAdmin"
+
+	return s.updateUser(op, userID, func(user *models.User) { user.IsAdmin = isAdmin })
+}
+
+func (s *Storage) SetUserDisabled(_ context.Context, userID int64, disabled bool) error {
+	const op = "storage.memory.SetUserDisabled"
+
+	return s.updateUser(op, userID, func(user *models.User) { user.Disabled = disabled })
+}
+
+// DeleteUser removes userID together with their roles, sessions and refresh
+// tokens.
+func (s *Storage) DeleteUser(_ context.Context, userID int64) error {
+	const op = "storage.memory.DeleteUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	delete(s.users, userID)
+	delete(s.userEmails, user.Email)
+	delete(s.userRoles, userID)
+	delete(s.totps, userID)
+	delete(s.recoveryCodes, userID)
+
+	for hash, challenge := range s.mfaChallenges {
+		if challenge.UserID == userID {
+			delete(s.mfaChallenges, hash)
+		}
+	}
+
+	for id, passkey := range s.passkeys {
+		if passkey.UserID == userID {
+			delete(s.passkeys, id)
+		}
+	}
+
+	for hash, ceremony := range s.ceremonies {
+		if ceremony.UserID == userID {
+			delete(s.ceremonies, hash)
+		}
+	}
+
+	for hash, verification := range s.emailVerifications {
+		if verification.UserID == userID {
+			delete(s.emailVerifications, hash)
+		}
+	}
+
+	for hash, reset := range s.passwordResets {
+		if reset.UserID == userID {
+			delete(s.passwordResets, hash)
+		}
+	}
+
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+			delete(s.sessionHashes, string(session.TokenHash))
+		}
+	}
+
+	for id, token := range s.refreshTokens {
+		if token.UserID == userID {
+			delete(s.refreshTokens, id)
+			delete(s.refreshTokenHashes, string(token.TokenHash))
+		}
+	}
+
+	return nil
+}
+
+// RevokeUserSessions ends every SSO session of userID and revokes all of
+// their refresh tokens.
+func (s *Storage) RevokeUserSessions(_ context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeSessions(userID, 0)
+
+	return nil
+}
+
+// revokeSessions ends the SSO sessions of userID other than keepSessionID,
+// which is zero to end them all, and revokes all of their refresh tokens.
+// s.mu must be held.
+func (s *Storage) revokeSessions(userID, keepSessionID int64) {
+	for id, session := range s.sessions {
+		if session.UserID == userID && id != keepSessionID {
+			session.Revoked = true
+			s.sessions[id] = session
+		}
+	}
+
+	for id, token := range s.refreshTokens {
+		if token.UserID == userID {
+			token.Revoked = true
+			s.refreshTokens[id] = token
+		}
+	}
+}
+
+func (s *Storage) updateUser(op string, userID int64, update func(user *models.User)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	update(&user)
+	s.users[userID] = user
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+func (s *Storage) SaveEmailVerification(_ context.Context, verification models.EmailVerification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.emailVerifications[string(verification.TokenHash)] = verification
+
+	return nil
+}
+
+// TakeEmailVerification removes the verification and returns it, so that its
+// token can only be used once. storage.ErrEmailVerificationNotFound is
+// returned if there is no such verification.
+func (s *Storage) TakeEmailVerification(_ context.Context, tokenHash []byte) (models.EmailVerification, error) {
+	const op = "storage.memory.TakeEmailVerification"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	verification, ok := s.emailVerifications[string(tokenHash)]
+	if !ok {
+		return models.EmailVerification{}, fmt.Errorf("%s: %w", op, storage.ErrEmailVerificationNotFound)
+	}
+	delete(s.emailVerifications, string(tokenHash))
+
+	return verification, nil
+}
+
+// SetEmailVerified marks the email of userID as verified.
+// storage.ErrUserNotFound is returned if the user does not exist or no
+// longer has the address email.
+func (s *Storage) SetEmailVerified(_ context.Context, userID int64, email string) error {
+	const op = "storage.memory.SetEmailVerified"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || user.Email != email {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	user.EmailVerified = true
+	s.users[userID] = user
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sso/internal/domain/models"
+)
+
+var auditEventsStmt = prepare("SELECT id, user_id, action, method, created_at FROM audit_events WHERE user_id = $1 ORDER BY id")
+
+// AuditEvents returns the audit events of userID, oldest first.
+func (s *Storage) AuditEvents(ctx context.Context, userID int64) ([]models.AuditEvent, error) {
+	const op = "storage.postgres.AuditEvents"
+
+	rows, err := s.stmts[auditEventsStmt].QueryContext(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Action, &event.Method, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,259 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/lib/envelope"
+)
+
+// dataKeys caches unwrapped data keys. The active data key is the newest
+// one wrapped by the primary master key; it encrypts every new value.
+type dataKeys struct {
+	active int64
+	keys   map[int64][]byte
+}
+
+// encrypt seals plaintext with the active data key and returns the
+// ciphertext together with the ID of the data key.
+func (s *Storage) encrypt(ctx context.Context, plaintext []byte) ([]byte, int64, error) {
+	id, key, err := s.activeDataKey(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sealed, err := envelope.Seal(key, plaintext)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sealed, id, nil
+}
+
+// decrypt opens a value sealed by encrypt. Values without a data key were
+// written before encryption was introduced and are returned as is.
+func (s *Storage) decrypt(ctx context.Context, value []byte, dataKeyID sql.NullInt64) ([]byte, error) {
+	if !dataKeyID.Valid {
+		return value, nil
+	}
+
+	key, err := s.dataKey(ctx, dataKeyID.Int64)
+	if err != nil {
+		return nil, err
+	}
+
+	return envelope.Open(key, value)
+}
+
+func (s *Storage) activeDataKey(ctx context.Context) (int64, []byte, error) {
+	s.mu.RLock()
+	id := s.dataKeys.active
+	key, ok := s.dataKeys.keys[id]
+	s.mu.RUnlock()
+
+	if ok {
+		return id, key, nil
+	}
+
+	var wrapped []byte
+	err := s.db.QueryRowContext(ctx, `SELECT id, wrapped_key FROM data_keys
+		WHERE master_key_id = $1 ORDER BY id DESC LIMIT 1`, s.keyring.PrimaryID()).Scan(&id, &wrapped)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		id, key, err = s.createDataKey(ctx)
+	case err == nil:
+		key, err = s.keyring.Unwrap(wrapped, s.keyring.PrimaryID())
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	s.mu.Lock()
+	s.dataKeys.active = id
+	s.dataKeys.keys[id] = key
+	s.mu.Unlock()
+
+	return id, key, nil
+}
+
+func (s *Storage) createDataKey(ctx context.Context) (int64, []byte, error) {
+	key, err := envelope.NewDataKey()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	wrapped, err := s.keyring.Wrap(key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, "INSERT INTO data_keys (wrapped_key, master_key_id) VALUES ($1, $2) RETURNING id",
+		wrapped, s.keyring.PrimaryID()).Scan(&id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return id, key, nil
+}
+
+func (s *Storage) dataKey(ctx context.Context, id int64) ([]byte, error) {
+	s.mu.RLock()
+	key, ok := s.dataKeys.keys[id]
+	s.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	var (
+		wrapped     []byte
+		masterKeyID string
+	)
+	err := s.db.QueryRowContext(ctx, "SELECT wrapped_key, master_key_id FROM data_keys WHERE id = $1", id).
+		Scan(&wrapped, &masterKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("data key %d: %w", id, err)
+	}
+
+	key, err = s.keyring.Unwrap(wrapped, masterKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("data key %d: %w", id, err)
+	}
+
+	s.mu.Lock()
+	s.dataKeys.keys[id] = key
+	s.mu.Unlock()
+
+	return key, nil
+}
+
+// Rekey re-wraps every data key that is not wrapped by the primary master
+// key and encrypts app secrets and signing keys that are still stored in
+// plaintext. It returns the number of data keys re-wrapped and of values
+// encrypted. Once it has run, previous master keys can be dropped from the
+// configuration.
+func (s *Storage) Rekey(ctx context.Context) (rewrapped int64, encrypted int64, err error) {
+	const op = "storage.postgres.Rekey"
+
+	rewrapped, err = s.rewrapDataKeys(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	secrets, err := s.encryptPlaintext(ctx, "app_secrets", "id", "secret")
+	if err != nil {
+		return rewrapped, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	signingKeys, err := s.encryptPlaintext(ctx, "signing_keys", "kid", "private_key")
+	if err != nil {
+		return rewrapped, secrets, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rewrapped, secrets + signingKeys, nil
+}
+
+func (s *Storage) rewrapDataKeys(ctx context.Context) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, wrapped_key, master_key_id FROM data_keys
+		WHERE master_key_id <> $1 FOR UPDATE`, s.keyring.PrimaryID())
+	if err != nil {
+		return 0, err
+	}
+
+	type stale struct {
+		id          int64
+		wrapped     []byte
+		masterKeyID string
+	}
+
+	var keys []stale
+	for rows.Next() {
+		var k stale
+		if err := rows.Scan(&k.id, &k.wrapped, &k.masterKeyID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, k := range keys {
+		key, err := s.keyring.Unwrap(k.wrapped, k.masterKeyID)
+		if err != nil {
+			return 0, fmt.Errorf("data key %d: %w", k.id, err)
+		}
+
+		wrapped, err := s.keyring.Wrap(key)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE data_keys SET wrapped_key = $2, master_key_id = $3 WHERE id = $1",
+			k.id, wrapped, s.keyring.PrimaryID())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int64(len(keys)), nil
+}
+
+// encryptPlaintext encrypts the column of every row of table that has no
+// data key yet. table, idColumn and column are trusted identifiers.
+func (s *Storage) encryptPlaintext(ctx context.Context, table, idColumn, column string) (int64, error) {
+	rows, err := s.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT %s::text, %s FROM %s WHERE data_key_id IS NULL", idColumn, column, table))
+	if err != nil {
+		return 0, err
+	}
+
+	type plaintext struct {
+		id    string
+		value []byte
+	}
+
+	var values []plaintext
+	for rows.Next() {
+		var v plaintext
+		if err := rows.Scan(&v.id, &v.value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		values = append(values, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET %s = $2, data_key_id = $3 WHERE %s = $1 AND data_key_id IS NULL",
+		table, column, idColumn)
+
+	for _, v := range values {
+		sealed, dataKeyID, err := s.encrypt(ctx, v.value)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := s.db.ExecContext(ctx, update, v.id, sealed, dataKeyID); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(values)), nil
+}
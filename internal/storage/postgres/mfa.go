@@ -0,0 +1,271 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+var saveTOTPStmt = prepare(`INSERT INTO user_totp (user_id, secret, data_key_id, created_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id) DO UPDATE
+		SET secret = excluded.secret, data_key_id = excluded.data_key_id, created_at = excluded.created_at, last_step = 0,
+			failures = 0, last_failure_at = NULL
+		WHERE user_totp.confirmed_at IS NULL`)
+
+// SaveTOTP stores the unconfirmed authenticator of a user, replacing one
+// that was never confirmed. storage.ErrTOTPConfirmed is returned if the user
+// already has a confirmed authenticator.
+func (s *Storage) SaveTOTP(ctx context.Context, totp models.TOTP) error {
+	const op = "storage.postgres.SaveTOTP"
+
+	secret, dataKeyID, err := s.encrypt(ctx, totp.Secret)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.stmts[saveTOTPStmt].ExecContext(ctx, totp.UserID, secret, dataKeyID, totp.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPConfirmed)
+	}
+
+	return nil
+}
+
+var totpStmt = prepare(`SELECT user_id, secret, data_key_id, created_at, confirmed_at, last_step, failures, last_failure_at
+	FROM user_totp WHERE user_id = $1`)
+
+func (s *Storage) TOTP(ctx context.Context, userID int64) (models.TOTP, error) {
+	const op = "storage.postgres.TOTP"
+
+	var (
+		totp                       models.TOTP
+		sealed                     []byte
+		dataKeyID                  sql.NullInt64
+		confirmedAt, lastFailureAt sql.NullTime
+	)
+	err := s.stmts[totpStmt].QueryRowContext(ctx, userID).Scan(
+		&totp.UserID, &sealed, &dataKeyID, &totp.CreatedAt, &confirmedAt, &totp.LastStep,
+		&totp.Failures, &lastFailureAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTP{}, fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+		}
+		return models.TOTP{}, fmt.Errorf("%s: %w", op, err)
+	}
+	totp.ConfirmedAt = confirmedAt.Time
+	totp.LastFailureAt = lastFailureAt.Time
+
+	totp.Secret, err = s.decrypt(ctx, sealed, dataKeyID)
+	if err != nil {
+		return models.TOTP{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return totp, nil
+}
+
+// ConfirmTOTP marks the authenticator of userID as confirmed with a code of
+// step and replaces the user's recovery codes with recoveryCodeHashes.
+// storage.ErrTOTPNotFound is returned if the user has no unconfirmed
+// authenticator.
+func (s *Storage) ConfirmTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes [][]byte) error {
+	const op = "storage.postgres.ConfirmTOTP"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE user_totp SET confirmed_at = now(), last_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL`, userID, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var useTOTPStepStmt = prepare("UPDATE user_totp SET last_step = $2 WHERE user_id = $1 AND last_step < $2")
+
+// UseTOTPStep records that a code of step was accepted for userID.
+// storage.ErrTOTPStepUsed is returned if a code of step or a later one was
+// accepted before.
+func (s *Storage) UseTOTPStep(ctx context.Context, userID int64, step int64) error {
+	const op = "storage.postgres.UseTOTPStep"
+
+	res, err := s.stmts[useTOTPStepStmt].ExecContext(ctx, userID, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPStepUsed)
+	}
+
+	return nil
+}
+
+var failTOTPStmt = prepare("UPDATE user_totp SET failures = failures + 1, last_failure_at = $2 WHERE user_id = $1")
+
+// FailTOTP records that a wrong second factor was given for userID at at.
+// storage.ErrTOTPNotFound is returned if the user has no authenticator.
+func (s *Storage) FailTOTP(ctx context.Context, userID int64, at time.Time) error {
+	const op = "storage.postgres.FailTOTP"
+
+	res, err := s.stmts[failTOTPStmt].ExecContext(ctx, userID, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, res, storage.ErrTOTPNotFound)
+}
+
+var resetTOTPFailuresStmt = prepare("UPDATE user_totp SET failures = 0 WHERE user_id = $1")
+
+// ResetTOTPFailures forgets the wrong second factors given for userID.
+func (s *Storage) ResetTOTPFailures(ctx context.Context, userID int64) error {
+	const op = "storage.postgres.ResetTOTPFailures"
+
+	if _, err := s.stmts[resetTOTPFailuresStmt].ExecContext(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var useRecoveryCodeStmt = prepare(`UPDATE recovery_codes SET used_at = now()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`)
+
+// UseRecoveryCode marks a recovery code of userID as used.
+// storage.ErrRecoveryCodeNotFound is returned if there is no such code or it
+// was used before.
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID int64, codeHash []byte) error {
+	const op = "storage.postgres.UseRecoveryCode"
+
+	res, err := s.stmts[useRecoveryCodeStmt].ExecContext(ctx, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRecoveryCodeNotFound)
+	}
+
+	return nil
+}
+
+var saveMFAChallengeStmt = prepare(`INSERT INTO mfa_challenges (token_hash, user_id, app_id, expires_at)
+	VALUES ($1, $2, $3, $4)`)
+
+func (s *Storage) SaveMFAChallenge(ctx context.Context, challenge models.MFAChallenge) error {
+	const op = "storage.postgres.SaveMFAChallenge"
+
+	_, err := s.stmts[saveMFAChallengeStmt].ExecContext(ctx, challenge.TokenHash, challenge.UserID, challenge.AppID, challenge.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var mfaChallengeStmt = prepare(`SELECT token_hash, user_id, app_id, expires_at, attempts
+	FROM mfa_challenges WHERE token_hash = $1`)
+
+func (s *Storage) MFAChallenge(ctx context.Context, tokenHash []byte) (models.MFAChallenge, error) {
+	const op = "storage.postgres.MFAChallenge"
+
+	var challenge models.MFAChallenge
+	err := s.stmts[mfaChallengeStmt].QueryRowContext(ctx, tokenHash).Scan(
+		&challenge.TokenHash, &challenge.UserID, &challenge.AppID, &challenge.ExpiresAt, &challenge.Attempts,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, storage.ErrMFAChallengeNotFound)
+		}
+		return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return challenge, nil
+}
+
+var failMFAChallengeStmt = prepare("UPDATE mfa_challenges SET attempts = attempts + 1 WHERE token_hash = $1")
+
+// FailMFAChallenge records a wrong code presented for the challenge.
+func (s *Storage) FailMFAChallenge(ctx context.Context, tokenHash []byte) error {
+	const op = "storage.postgres.FailMFAChallenge"
+
+	if _, err := s.stmts[failMFAChallengeStmt].ExecContext(ctx, tokenHash); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var deleteMFAChallengeStmt = prepare("DELETE FROM mfa_challenges WHERE token_hash = $1")
+
+// DeleteMFAChallenge removes a completed challenge.
+// storage.ErrMFAChallengeNotFound is returned if it was already removed,
+// which means it was completed concurrently.
+func (s *Storage) DeleteMFAChallenge(ctx context.Context, tokenHash []byte) error {
+	const op = "storage.postgres.DeleteMFAChallenge"
+
+	res, err := s.stmts[deleteMFAChallengeStmt].ExecContext(ctx, tokenHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrMFAChallengeNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+// ChangePassword replaces the password hash of the user, discards the
+// password resets still pending for them, ends their SSO sessions other than
+// change.KeepSessionID, revokes their refresh tokens and records change.Event,
+// all in one transaction.
+func (s *Storage) ChangePassword(ctx context.Context, change models.PasswordChange) error {
+	const op = "storage.postgres.ChangePassword"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE users SET pass_hash = $2 WHERE id = $1", change.UserID, change.PassHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := expectAffected(op, res, storage.ErrUserNotFound); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_resets WHERE user_id = $1", change.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := revokeSessions(ctx, tx, change.UserID, change.KeepSessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO audit_events (user_id, action, method, created_at) VALUES ($1, $2, $3, $4)",
+		change.UserID, change.Event.Action, change.Event.Method, change.Event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+var savePasswordResetStmt = prepare("INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES ($1, $2, $3)")
+
+func (s *Storage) SavePasswordReset(ctx context.Context, reset models.PasswordReset) error {
+	const op = "storage.postgres.SavePasswordReset"
+
+	_, err := s.stmts[savePasswordResetStmt].ExecContext(ctx, reset.TokenHash, reset.UserID, reset.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var takePasswordResetStmt = prepare(`DELETE FROM password_resets WHERE token_hash = $1
+	RETURNING token_hash, user_id, expires_at`)
+
+// TakePasswordReset removes the reset and returns it, so that its token can
+// only be used once. storage.ErrPasswordResetNotFound is returned if there is
+// no such reset.
+func (s *Storage) TakePasswordReset(ctx context.Context, tokenHash []byte) (models.PasswordReset, error) {
+	const op = "storage.postgres.TakePasswordReset"
+
+	var reset models.PasswordReset
+	err := s.stmts[takePasswordResetStmt].QueryRowContext(ctx, tokenHash).Scan(
+		&reset.TokenHash, &reset.UserID, &reset.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PasswordReset{}, fmt.Errorf("%s: %w", op, storage.ErrPasswordResetNotFound)
+		}
+		return models.PasswordReset{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reset, nil
+}
@@ -5,100 +5,131 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"sso/internal/domain/models"
+	"sso/internal/lib/envelope"
 	"sso/internal/storage"
+	"sync"
+	"time"
 )
 
-const (
-	host     = "localhost"
-	port     = 5432
-	user     = "postgres"
-	password = "pwd"
-	dbname   = "test"
-)
+// pingTimeout bounds how long New waits for the database to answer.
+const pingTimeout = 5 * time.Second
+
+var saveUserStmt = prepare("INSERT INTO users (email, pass_hash) VALUES ($1, $2) RETURNING id")
 
 type Storage struct {
-	db *sql.DB
+	db      *sql.DB
+	stmts   statements
+	keyring *envelope.Keyring
+
+	mu       sync.RWMutex
+	dataKeys dataKeys
+}
+
+// PoolConfig limits the connections kept to the database.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
-func New() (*Storage, error) {
+// New connects to the database at dsn and checks that it is reachable. App
+// secrets and signing keys are encrypted with data keys wrapped by the
+// master keys in keyring.
+func New(dsn string, pool PoolConfig, keyring *envelope.Keyring) (*Storage, error) {
 	const op = "storage.postgres.New"
 
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s "+
-		"password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
-	db, err := sql.Open("postgres", psqlInfo)
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	fmt.Println("successfully connected to DB")
 
-	return &Storage{db: db}, nil
-}
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
-func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (int64, error) {
-	const op = "storage.postgres.SaveUser"
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
 
-	if ok, err := s.userNotExists(ctx, email); !ok {
-		return 0, err
-	}
-
-	stmt, err := s.db.Prepare("INSERT INTO users (email, pass_hash) VALUES ($1, $2);")
-	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
-	}
-
-	_, err = stmt.ExecContext(ctx, email, passHash)
-	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	var uid int64
-	stmt, err = s.db.PrepareContext(ctx, "SELECT id FROM users WHERE email = $1;")
-	err = stmt.QueryRowContext(ctx, email).Scan(&uid)
+	stmts, err := prepareStatements(ctx, db)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return uid, nil
+	return &Storage{
+		db:       db,
+		stmts:    stmts,
+		keyring:  keyring,
+		dataKeys: dataKeys{keys: make(map[int64][]byte)},
+	}, nil
 }
 
-func (s *Storage) userNotExists(ctx context.Context, email string) (bool, error) {
-	const op = "storage.postgres.userExists"
+// Close closes the prepared statements and the connections to the database.
+func (s *Storage) Close() error {
+	return errors.Join(s.stmts.close(), s.db.Close())
+}
 
-	stmt, err := s.db.Prepare("SELECT id FROM users WHERE email = $1")
-	if err != nil {
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
+// SaveUser creates a user and returns its ID. storage.ErrUserExists is
+// returned if the email is already taken.
+func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (int64, error) {
+	const op = "storage.postgres.SaveUser"
 
 	var uid int64
-	err = stmt.QueryRowContext(ctx, email).Scan(&uid)
+	err := s.stmts[saveUserStmt].QueryRowContext(ctx, email, passHash).Scan(&uid)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return true, nil
-		} else {
-			return false, fmt.Errorf("%s: %w", op, err)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
 		}
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return false, storage.ErrUserExists
+	return uid, nil
 }
 
+var userStmt = prepare("SELECT id, email, pass_hash, is_admin, disabled, email_verified FROM users WHERE email = $1")
+
 func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 	const op = "storage.postgres.User"
 
 	var user models.User
 
-	stmt, err := s.db.Prepare("SELECT id, email, pass_hash FROM users WHERE email = $1")
+	err := s.stmts[userStmt].QueryRowContext(ctx, email).Scan(
+		&user.ID, &user.Email, &user.PassHash, &user.IsAdmin, &user.Disabled, &user.EmailVerified,
+	)
+
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, storage.ErrUserNotFound
+		}
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = stmt.QueryRowContext(ctx, email).Scan(&user.ID, &user.Email, &user.PassHash)
+	return user, nil
+}
+
+var userByIDStmt = prepare("SELECT id, email, pass_hash, is_admin, disabled, email_verified FROM users WHERE id = $1")
 
+func (s *Storage) UserByID(ctx context.Context, userID int64) (models.User, error) {
+	const op = "storage.postgres.UserByID"
+
+	var user models.User
+
+	err := s.stmts[userByIDStmt].QueryRowContext(ctx, userID).Scan(
+		&user.ID, &user.Email, &user.PassHash, &user.IsAdmin, &user.Disabled, &user.EmailVerified,
+	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.User{}, storage.ErrUserNotFound
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 		}
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -106,16 +137,13 @@ func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 	return user, nil
 }
 
+var isAdminStmt = prepare("SELECT is_admin FROM users WHERE id = $1")
+
 func (s *Storage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	const op = "storage.postgres.IsAdmin"
 
-	stmt, err := s.db.Prepare("SELECT is_admin FROM users WHERE id = $1")
-	if err != nil {
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
-
 	var isAdmin bool
-	err = stmt.QueryRowContext(ctx, userID).Scan(&isAdmin)
+	err := s.stmts[isAdminStmt].QueryRowContext(ctx, userID).Scan(&isAdmin)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -126,22 +154,30 @@ func (s *Storage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	return isAdmin, nil
 }
 
+var appStmt = prepare("SELECT " + appColumns + " FROM apps WHERE id = $1")
+
 func (s *Storage) App(ctx context.Context, appID int64) (models.App, error) {
 	const op = "storage.postgres.App"
 
-	stmt, err := s.db.Prepare("SELECT id, name, secret FROM apps WHERE id = $1")
+	app, err := scanApp(s.stmts[appStmt].QueryRowContext(ctx, appID))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	var app models.App
-	err = stmt.QueryRowContext(ctx, appID).Scan(&app.ID, &app.Name, &app.Secret)
+	app.Secrets, err = s.AppSecrets(ctx, appID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
-		}
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	for _, secret := range app.Secrets {
+		if secret.State == models.AppSecretActive {
+			app.Secret = secret.Secret
+			break
+		}
+	}
+
 	return app, nil
 }
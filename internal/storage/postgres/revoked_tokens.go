@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var purgeRevokedTokensStmt = prepare("DELETE FROM revoked_tokens WHERE expires_at < now()")
+
+var revokeTokenStmt = prepare(`INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+	ON CONFLICT (jti) DO NOTHING`)
+
+// RevokeToken adds jti to the denylist until expiresAt. Entries whose token
+// has already expired are purged on the way, so the table stays small.
+func (s *Storage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "storage.postgres.RevokeToken"
+
+	if _, err := s.stmts[purgeRevokedTokensStmt].ExecContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err := s.stmts[revokeTokenStmt].ExecContext(ctx, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var isTokenRevokedStmt = prepare("SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at >= now())")
+
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	const op = "storage.postgres.IsTokenRevoked"
+
+	var revoked bool
+	if err := s.stmts[isTokenRevokedStmt].QueryRowContext(ctx, jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+var saveSessionStmt = prepare(`INSERT INTO sessions (token_hash, user_id, created_at, last_seen_at, expires_at)
+	VALUES ($1, $2, $3, $3, $4)`)
+
+func (s *Storage) SaveSession(ctx context.Context, session models.Session) error {
+	const op = "storage.postgres.SaveSession"
+
+	_, err := s.stmts[saveSessionStmt].ExecContext(ctx, session.TokenHash, session.UserID, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var sessionStmt = prepare(`SELECT id, token_hash, user_id, created_at, last_seen_at, expires_at, revoked_at IS NOT NULL
+	FROM sessions WHERE token_hash = $1`)
+
+func (s *Storage) Session(ctx context.Context, tokenHash []byte) (models.Session, error) {
+	const op = "storage.postgres.Session"
+
+	var session models.Session
+	err := s.stmts[sessionStmt].QueryRowContext(ctx, tokenHash).Scan(
+		&session.ID, &session.TokenHash, &session.UserID,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.Revoked,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+var touchSessionStmt = prepare(`UPDATE sessions SET last_seen_at = now()
+	WHERE id = $1 AND revoked_at IS NULL AND expires_at > now() AND last_seen_at > $2`)
+
+// TouchSession records a use of the session. storage.ErrSessionNotFound is
+// returned if the session is revoked, expired or was last seen before idleSince.
+func (s *Storage) TouchSession(ctx context.Context, sessionID int64, idleSince time.Time) error {
+	const op = "storage.postgres.TouchSession"
+
+	res, err := s.stmts[touchSessionStmt].ExecContext(ctx, sessionID, idleSince)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+var revokeSessionStmt = prepare("UPDATE sessions SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL")
+
+func (s *Storage) RevokeSession(ctx context.Context, sessionID int64) error {
+	const op = "storage.postgres.RevokeSession"
+
+	if _, err := s.stmts[revokeSessionStmt].ExecContext(ctx, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
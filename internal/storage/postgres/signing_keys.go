@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+var saveSigningKeyStmt = prepare(`INSERT INTO signing_keys (kid, algorithm, state, private_key, data_key_id, public_key, created_at, activated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`)
+
+func (s *Storage) SaveSigningKey(ctx context.Context, key models.SigningKey) error {
+	const op = "storage.postgres.SaveSigningKey"
+
+	privateKey, dataKeyID, err := s.encrypt(ctx, key.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.stmts[saveSigningKeyStmt].ExecContext(ctx, key.ID, key.Algorithm, key.State, privateKey, dataKeyID, key.PublicKey, key.CreatedAt, nullTime(key.ActivatedAt))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var signingKeysStmt = prepare(`SELECT kid, algorithm, state, private_key, data_key_id, public_key, created_at, activated_at, retire_at
+	FROM signing_keys WHERE state <> $1 ORDER BY created_at`)
+
+// SigningKeys returns all keys that are not retired, oldest first.
+func (s *Storage) SigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	const op = "storage.postgres.SigningKeys"
+
+	rows, err := s.stmts[signingKeysStmt].QueryContext(ctx, models.KeyStateRetired)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []models.SigningKey
+	for rows.Next() {
+		var (
+			key         models.SigningKey
+			sealed      []byte
+			dataKeyID   sql.NullInt64
+			activatedAt sql.NullTime
+			retireAt    sql.NullTime
+		)
+		err := rows.Scan(&key.ID, &key.Algorithm, &key.State, &sealed, &dataKeyID, &key.PublicKey,
+			&key.CreatedAt, &activatedAt, &retireAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		key.ActivatedAt = activatedAt.Time
+		key.RetireAt = retireAt.Time
+
+		key.PrivateKey, err = s.decrypt(ctx, sealed, dataKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RotateSigningKeys activates the pending key promote, moves every other
+// active key to retiring until retireAt and saves next as the new pending key.
+// storage.ErrSigningKeyNotFound is returned if promote is no longer pending,
+// which means another replica has already rotated.
+func (s *Storage) RotateSigningKeys(ctx context.Context, promote string, retireAt time.Time, next models.SigningKey) error {
+	const op = "storage.postgres.RotateSigningKeys"
+
+	privateKey, dataKeyID, err := s.encrypt(ctx, next.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE signing_keys SET state = $1, activated_at = now() WHERE kid = $2 AND state = $3",
+		models.KeyStateActive, promote, models.KeyStatePending)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSigningKeyNotFound)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE signing_keys SET state = $1, retire_at = $2 WHERE state = $3 AND kid <> $4",
+		models.KeyStateRetiring, retireAt, models.KeyStateActive, promote)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO signing_keys (kid, algorithm, state, private_key, data_key_id, public_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		next.ID, next.Algorithm, models.KeyStatePending, privateKey, dataKeyID, next.PublicKey, next.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var retireSigningKeysStmt = prepare("UPDATE signing_keys SET state = $1 WHERE state = $2 AND retire_at <= now()")
+
+// RetireSigningKeys moves retiring keys whose retire_at has passed to retired.
+func (s *Storage) RetireSigningKeys(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.RetireSigningKeys"
+
+	res, err := s.stmts[retireSigningKeysStmt].ExecContext(ctx, models.KeyStateRetired, models.KeyStateRetiring)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	retired, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return retired, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// uniqueViolation is the Postgres error code for a violated unique
+// constraint.
+const uniqueViolation = "23505"
+
+// stmt identifies a statement registered with prepare.
+type stmt int
+
+// queries holds the SQL of every registered statement, indexed by stmt.
+var queries []string
+
+// prepare registers query to be prepared when a Storage is created. It must
+// only be called while initializing package variables.
+func prepare(query string) stmt {
+	queries = append(queries, query)
+	return stmt(len(queries) - 1)
+}
+
+// statements holds the prepared form of every registered query.
+type statements []*sql.Stmt
+
+func prepareStatements(ctx context.Context, db *sql.DB) (statements, error) {
+	stmts := make(statements, 0, len(queries))
+
+	for _, query := range queries {
+		prepared, err := db.PrepareContext(ctx, query)
+		if err != nil {
+			stmts.close()
+			return nil, fmt.Errorf("prepare %q: %w", query, err)
+		}
+		stmts = append(stmts, prepared)
+	}
+
+	return stmts, nil
+}
+
+func (stmts statements) close() error {
+	var errs []error
+	for _, prepared := range stmts {
+		if err := prepared.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+var usersStmt = prepare("SELECT id, email, is_admin, disabled, email_verified FROM users WHERE id > $1 ORDER BY id LIMIT $2")
+
+// Users returns up to limit users with an ID greater than afterID, ordered by
+// ID. Password hashes are not loaded.
+func (s *Storage) Users(ctx context.Context, afterID int64, limit int) ([]models.User, error) {
+	const op = "storage.postgres.Users"
+
+	rows, err := s.stmts[usersStmt].QueryContext(ctx, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.IsAdmin, &user.Disabled, &user.EmailVerified); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+var setAdminStmt = prepare("UPDATE users SET is_admin = $2 WHERE id = $1")
+
+func (s *Storage) SetAdmin(ctx context.Context, userID int64, isAdmin bool) error {
+	const op = "storage.postgres.SetAdmin"
+
+	res, err := s.stmts[setAdminStmt].ExecContext(ctx, userID, isAdmin)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, res, storage.ErrUserNotFound)
+}
+
+var setUserDisabledStmt = prepare("UPDATE users SET disabled = $2 WHERE id = $1")
+
+func (s *Storage) SetUserDisabled(ctx context.Context, userID int64, disabled bool) error {
+	const op = "storage.postgres.SetUserDisabled"
+
+	res, err := s.stmts[setUserDisabledStmt].ExecContext(ctx, userID, disabled)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, res, storage.ErrUserNotFound)
+}
+
+var deleteUserStmt = prepare("DELETE FROM users WHERE id = $1")
+
+func (s *Storage) DeleteUser(ctx context.Context, userID int64) error {
+	const op = "storage.postgres.DeleteUser"
+
+	res, err := s.stmts[deleteUserStmt].ExecContext(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, res, storage.ErrUserNotFound)
+}
+
+// RevokeUserSessions ends every SSO session of userID and revokes all of
+// their refresh tokens.
+func (s *Storage) RevokeUserSessions(ctx context.Context, userID int64) error {
+	const op = "storage.postgres.RevokeUserSessions"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := revokeSessions(ctx, tx, userID, 0); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// revokeSessions ends the SSO sessions of userID other than keepSessionID,
+// which is zero to end them all, and revokes all of their refresh tokens.
+func revokeSessions(ctx context.Context, tx *sql.Tx, userID, keepSessionID int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL",
+		userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+
+	return err
+}
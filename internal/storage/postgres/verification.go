@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+var saveEmailVerificationStmt = prepare(`INSERT INTO email_verifications (token_hash, user_id, email, expires_at)
+	VALUES ($1, $2, $3, $4)`)
+
+func (s *Storage) SaveEmailVerification(ctx context.Context, verification models.EmailVerification) error {
+	const op = "storage.postgres.SaveEmailVerification"
+
+	_, err := s.stmts[saveEmailVerificationStmt].ExecContext(ctx, verification.TokenHash, verification.UserID,
+		verification.Email, verification.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var takeEmailVerificationStmt = prepare(`DELETE FROM email_verifications WHERE token_hash = $1
+	RETURNING token_hash, user_id, email, expires_at`)
+
+// TakeEmailVerification removes the verification and returns it, so that its
+// token can only be used once. storage.ErrEmailVerificationNotFound is
+// returned if there is no such verification.
+func (s *Storage) TakeEmailVerification(ctx context.Context, tokenHash []byte) (models.EmailVerification, error) {
+	const op = "storage.postgres.TakeEmailVerification"
+
+	var verification models.EmailVerification
+	err := s.stmts[takeEmailVerificationStmt].QueryRowContext(ctx, tokenHash).Scan(
+		&verification.TokenHash, &verification.UserID, &verification.Email, &verification.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.EmailVerification{}, fmt.Errorf("%s: %w", op, storage.ErrEmailVerificationNotFound)
+		}
+		return models.EmailVerification{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return verification, nil
+}
+
+var setEmailVerifiedStmt = prepare("UPDATE users SET email_verified = TRUE WHERE id = $1 AND email = $2")
+
+// SetEmailVerified marks the email of userID as verified.
+// storage.ErrUserNotFound is returned if the user does not exist or no
+// longer has the address email.
+func (s *Storage) SetEmailVerified(ctx context.Context, userID int64, email string) error {
+	const op = "storage.postgres.SetEmailVerified"
+
+	res, err := s.stmts[setEmailVerifiedStmt].ExecContext(ctx, userID, email)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, res, storage.ErrUserNotFound)
+}
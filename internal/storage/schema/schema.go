@@ -0,0 +1,105 @@
+// Package schema brings the database schema up to date with the migrations
+// embedded in the binary.
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"sso/migrations"
+)
+
+// advisoryLockID identifies the Postgres advisory lock held while migrating.
+const advisoryLockID int64 = 0x73736f5f6d6967 // "sso_mig"
+
+// UpPostgres applies the pending migrations to the Postgres database at dsn
+// and returns the resulting version. Replicas that start at the same time
+// take turns under an advisory lock; the ones that follow the first find the
+// schema up to date.
+func UpPostgres(ctx context.Context, dsn string) (uint, error) {
+	const op = "storage.schema.UpPostgres"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return 0, fmt.Errorf("%s: lock: %w", op, err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockID)
+
+	// The driver is not closed: that would hand conn back to the pool while
+	// it still holds the lock.
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	version, err := up(driver, "postgres", migrations.PostgresDir)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// UpSQLite applies the pending migrations to the SQLite database file at
+// path, creating it if needed, and returns the resulting version.
+func UpSQLite(path string) (uint, error) {
+	const op = "storage.schema.UpSQLite"
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer db.Close()
+
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	version, err := up(driver, "sqlite", migrations.SQLiteDir)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+func up(driver database.Driver, driverName, dir string) (uint, error) {
+	source, err := iofs.New(migrations.FS, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, driverName, driver)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return 0, err
+	}
+
+	version, _, err := m.Version()
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
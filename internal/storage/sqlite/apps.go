@@ -0,0 +1,318 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"strings"
+	"time"
+)
+
+// SaveApp creates app with app.Secret as its active secret.
+// storage.ErrAppExists is returned if its ID or name is already taken.
+func (s *Storage) SaveApp(ctx context.Context, app models.App) error {
+	const op = "storage.sqlite.SaveApp"
+
+	secret, dataKeyID, err := s.encrypt(ctx, []byte(app.Secret))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO apps (id, name, token_ttl_seconds, scopes, issuer, audience, claims, require_verified_email)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING RETURNING id`,
+		app.ID, app.Name, ttlSeconds(app.TokenTTL), joinScopes(app.Scopes), app.Issuer, app.Audience, joinClaims(app.Claims),
+		app.RequireVerifiedEmail,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO app_secrets (app_id, secret, data_key_id, state, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		app.ID, secret, dataKeyID, models.AppSecretActive, unixNano(time.Now()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var appsStmt = prepare("SELECT " + appColumns + " FROM apps ORDER BY id")
+
+// Apps returns all apps ordered by ID. Secrets are not loaded.
+func (s *Storage) Apps(ctx context.Context) ([]models.App, error) {
+	const op = "storage.sqlite.Apps"
+
+	rows, err := s.stmts[appsStmt].QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var apps []models.App
+	for rows.Next() {
+		app, err := scanApp(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		apps = append(apps, app)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return apps, nil
+}
+
+var updateAppStmt = prepare("UPDATE apps SET name = $2 WHERE id = $1")
+
+func (s *Storage) UpdateApp(ctx context.Context, app models.App) error {
+	const op = "storage.sqlite.UpdateApp"
+
+	res, err := s.stmts[updateAppStmt].ExecContext(ctx, app.ID, app.Name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, res, storage.ErrAppNotFound)
+}
+
+var updateAppSettingsStmt = prepare(`UPDATE apps SET token_ttl_seconds = $2, scopes = $3, issuer = $4, audience = $5, claims = $6,
+	require_verified_email = $7
+	WHERE id = $1`)
+
+// UpdateAppSettings stores the token TTL, scopes, issuer, audience, claims
+// and email verification requirement of app.
+func (s *Storage) UpdateAppSettings(ctx context.Context, app models.App) error {
+	const op = "storage.sqlite.UpdateAppSettings"
+
+	res, err := s.stmts[updateAppSettingsStmt].ExecContext(ctx, app.ID, ttlSeconds(app.TokenTTL), joinScopes(app.Scopes),
+		app.Issuer, app.Audience, joinClaims(app.Claims), app.RequireVerifiedEmail)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, res, storage.ErrAppNotFound)
+}
+
+var deleteAppStmt = prepare("DELETE FROM apps WHERE id = $1")
+
+func (s *Storage) DeleteApp(ctx context.Context, appID int64) error {
+	const op = "storage.sqlite.DeleteApp"
+
+	res, err := s.stmts[deleteAppStmt].ExecContext(ctx, appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, res, storage.ErrAppNotFound)
+}
+
+var appSecretsStmt = prepare(`SELECT secret, data_key_id, state, created_at, expires_at FROM app_secrets
+	WHERE app_id = $1 AND (expires_at IS NULL OR expires_at > $2)
+	ORDER BY created_at DESC, id DESC`)
+
+// AppSecrets returns the secrets of app that have not expired, newest first.
+func (s *Storage) AppSecrets(ctx context.Context, appID int64) ([]models.AppSecret, error) {
+	const op = "storage.sqlite.AppSecrets"
+
+	rows, err := s.stmts[appSecretsStmt].QueryContext(ctx, appID, unixNano(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var secrets []models.AppSecret
+	for rows.Next() {
+		var (
+			secret    models.AppSecret
+			sealed    []byte
+			dataKeyID sql.NullInt64
+			createdAt sql.NullInt64
+			expiresAt sql.NullInt64
+		)
+		if err := rows.Scan(&sealed, &dataKeyID, &secret.State, &createdAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		secret.CreatedAt = fromUnixNano(createdAt)
+		secret.ExpiresAt = fromUnixNano(expiresAt)
+
+		plaintext, err := s.decrypt(ctx, sealed, dataKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		secret.Secret = string(plaintext)
+
+		secrets = append(secrets, secret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return secrets, nil
+}
+
+// RotateAppSecret makes secret the active secret of app. Secrets that were
+// active become verify-only and expire at retireAt.
+func (s *Storage) RotateAppSecret(ctx context.Context, appID int64, secret string, retireAt time.Time) error {
+	const op = "storage.sqlite.RotateAppSecret"
+
+	sealed, dataKeyID, err := s.encrypt(ctx, []byte(secret))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The transaction holds the write lock, so concurrent rotations cannot
+	// leave more than one secret active.
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM apps WHERE id = $1", appID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE app_secrets SET state = $2, expires_at = $3
+		WHERE app_id = $1 AND state = $4`,
+		appID, models.AppSecretVerifyOnly, unixNano(retireAt), models.AppSecretActive)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO app_secrets (app_id, secret, data_key_id, state, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		appID, sealed, dataKeyID, models.AppSecretActive, unixNano(time.Now()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var retireAppSecretsStmt = prepare(`DELETE FROM app_secrets
+	WHERE app_id = $1 AND (state = $2 OR expires_at <= $3)`)
+
+// RetireAppSecrets deletes the verify-only secrets of app, as well as any
+// expired ones, and returns how many were deleted. storage.ErrAppNotFound is
+// returned if there is no such app.
+func (s *Storage) RetireAppSecrets(ctx context.Context, appID int64) (int64, error) {
+	const op = "storage.sqlite.RetireAppSecrets"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM apps WHERE id = $1", appID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.StmtContext(ctx, s.stmts[retireAppSecretsStmt]).ExecContext(ctx, appID, models.AppSecretVerifyOnly, unixNano(time.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	retired, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return retired, nil
+}
+
+// appColumns are the columns scanApp reads.
+const appColumns = "id, name, token_ttl_seconds, scopes, issuer, audience, claims, require_verified_email"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanApp(row rowScanner) (models.App, error) {
+	var (
+		app      models.App
+		tokenTTL int64
+		scopes   string
+		claims   sql.NullString
+	)
+	err := row.Scan(&app.ID, &app.Name, &tokenTTL, &scopes, &app.Issuer, &app.Audience, &claims, &app.RequireVerifiedEmail)
+	if err != nil {
+		return models.App{}, err
+	}
+
+	app.TokenTTL = time.Duration(tokenTTL) * time.Second
+	app.Scopes = strings.Fields(scopes)
+	if claims.Valid {
+		app.Claims = append([]string{}, strings.Fields(claims.String)...)
+	}
+
+	return app, nil
+}
+
+// ttlSeconds converts ttl to the whole seconds it is stored as.
+func ttlSeconds(ttl time.Duration) int64 {
+	return int64(ttl / time.Second)
+}
+
+// joinScopes converts scopes to the space-separated list they are stored as.
+func joinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// joinClaims converts claims to the space-separated list they are stored
+// as. Nil, which stands for the default claims, is stored as NULL.
+func joinClaims(claims []string) sql.NullString {
+	return sql.NullString{String: strings.Join(claims, " "), Valid: claims != nil}
+}
+
+// expectAffected returns notFound wrapped in op if res affected no rows.
+func expectAffected(op string, res sql.Result, notFound error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, notFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package sqlite_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestRetireAppSecrets(t *testing.T) {
+	ctx := context.Background()
+	st := newStorage(t)
+
+	require.NoError(t, st.SaveApp(ctx, models.App{ID: 1, Name: "test", Secret: "old"}))
+	require.NoError(t, st.RotateAppSecret(ctx, 1, "new", time.Now().Add(time.Hour)))
+
+	retired, err := st.RetireAppSecrets(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), retired)
+
+	app, err := st.App(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "new", app.Secret)
+
+	_, err = st.RetireAppSecrets(ctx, 2)
+	assert.ErrorIs(t, err, storage.ErrAppNotFound)
+}
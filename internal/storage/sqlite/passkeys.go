@@ -0,0 +1,147 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"strings"
+	"time"
+)
+
+var savePasskeyStmt = prepare(`INSERT INTO passkeys
+	(user_id, credential_id, public_key, attestation_type, aaguid, sign_count, transports, backup_eligible, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+
+// SavePasskey stores a passkey registered by a user. storage.ErrPasskeyExists
+// is returned if the credential is already registered.
+func (s *Storage) SavePasskey(ctx context.Context, passkey models.Passkey) error {
+	const op = "storage.sqlite.SavePasskey"
+
+	_, err := s.stmts[savePasskeyStmt].ExecContext(ctx, passkey.UserID, passkey.CredentialID, passkey.PublicKey,
+		passkey.AttestationType, passkey.AAGUID, int64(passkey.SignCount), strings.Join(passkey.Transports, " "),
+		passkey.BackupEligible, unixNano(passkey.CreatedAt))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrPasskeyExists)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var passkeysStmt = prepare(`SELECT id, user_id, credential_id, public_key, attestation_type, aaguid, sign_count,
+	transports, backup_eligible, created_at, last_used_at
+	FROM passkeys WHERE user_id = $1 ORDER BY id`)
+
+// Passkeys returns the passkeys of userID, oldest first.
+func (s *Storage) Passkeys(ctx context.Context, userID int64) ([]models.Passkey, error) {
+	const op = "storage.sqlite.Passkeys"
+
+	rows, err := s.stmts[passkeysStmt].QueryContext(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var passkeys []models.Passkey
+	for rows.Next() {
+		var (
+			passkey               models.Passkey
+			signCount             int64
+			transports            string
+			createdAt, lastUsedAt sql.NullInt64
+		)
+		err := rows.Scan(&passkey.ID, &passkey.UserID, &passkey.CredentialID, &passkey.PublicKey,
+			&passkey.AttestationType, &passkey.AAGUID, &signCount, &transports, &passkey.BackupEligible,
+			&createdAt, &lastUsedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		passkey.SignCount = uint32(signCount)
+		passkey.Transports = strings.Fields(transports)
+		passkey.CreatedAt = fromUnixNano(createdAt)
+		passkey.LastUsedAt = fromUnixNano(lastUsedAt)
+
+		passkeys = append(passkeys, passkey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return passkeys, nil
+}
+
+var usePasskeyStmt = prepare(`UPDATE passkeys SET sign_count = $2, last_used_at = $3
+	WHERE id = $1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))`)
+
+// UsePasskey records a login with the passkey and the signature counter the
+// authenticator reported. storage.ErrPasskeySignCount is returned unless the
+// counter increased or the authenticator does not keep one, which hints at a
+// cloned authenticator or a concurrent login with the same response.
+func (s *Storage) UsePasskey(ctx context.Context, passkeyID int64, signCount uint32) error {
+	const op = "storage.sqlite.UsePasskey"
+
+	res, err := s.stmts[usePasskeyStmt].ExecContext(ctx, passkeyID, int64(signCount), unixNano(time.Now()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrPasskeySignCount)
+	}
+
+	return nil
+}
+
+var saveCeremonyStmt = prepare(`INSERT INTO webauthn_ceremonies (token_hash, user_id, app_id, session, expires_at)
+	VALUES ($1, $2, $3, $4, $5)`)
+
+func (s *Storage) SaveCeremony(ctx context.Context, ceremony models.WebAuthnCeremony) error {
+	const op = "storage.sqlite.SaveCeremony"
+
+	appID := sql.NullInt64{Int64: ceremony.AppID, Valid: ceremony.AppID != 0}
+
+	_, err := s.stmts[saveCeremonyStmt].ExecContext(ctx, ceremony.TokenHash, ceremony.UserID, appID,
+		ceremony.Session, unixNano(ceremony.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var takeCeremonyStmt = prepare(`DELETE FROM webauthn_ceremonies WHERE token_hash = $1
+	RETURNING token_hash, user_id, app_id, session, expires_at`)
+
+// TakeCeremony removes the ceremony and returns it, so that the response of
+// an authenticator can only be verified once. storage.ErrCeremonyNotFound is
+// returned if there is no such ceremony.
+func (s *Storage) TakeCeremony(ctx context.Context, tokenHash []byte) (models.WebAuthnCeremony, error) {
+	const op = "storage.sqlite.TakeCeremony"
+
+	var (
+		ceremony         models.WebAuthnCeremony
+		appID, expiresAt sql.NullInt64
+	)
+	err := s.stmts[takeCeremonyStmt].QueryRowContext(ctx, tokenHash).Scan(
+		&ceremony.TokenHash, &ceremony.UserID, &appID, &ceremony.Session, &expiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebAuthnCeremony{}, fmt.Errorf("%s: %w", op, storage.ErrCeremonyNotFound)
+		}
+		return models.WebAuthnCeremony{}, fmt.Errorf("%s: %w", op, err)
+	}
+	ceremony.AppID = appID.Int64
+	ceremony.ExpiresAt = fromUnixNano(expiresAt)
+
+	return ceremony, nil
+}
@@ -0,0 +1,112 @@
+package sqlite_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"sso/internal/domain/models"
+	"sso/internal/lib/envelope"
+	"sso/internal/storage"
+	"sso/internal/storage/schema"
+	"sso/internal/storage/sqlite"
+	"testing"
+	"time"
+)
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	st := newStorage(t)
+
+	userID, err := st.SaveUser(ctx, "user@example.com", []byte("old"))
+	require.NoError(t, err)
+
+	now := time.Now()
+	for _, hash := range []string{"kept", "other"} {
+		require.NoError(t, st.SaveSession(ctx, models.Session{
+			TokenHash:  []byte(hash),
+			UserID:     userID,
+			CreatedAt:  now,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(time.Hour),
+		}))
+	}
+	kept, err := st.Session(ctx, []byte("kept"))
+	require.NoError(t, err)
+
+	require.NoError(t, st.SavePasswordReset(ctx, models.PasswordReset{
+		TokenHash: []byte("reset"),
+		UserID:    userID,
+		ExpiresAt: now.Add(time.Hour),
+	}))
+
+	err = st.ChangePassword(ctx, models.PasswordChange{
+		UserID:        userID,
+		PassHash:      []byte("new"),
+		KeepSessionID: kept.ID,
+		Event: models.AuditEvent{
+			Action:    models.AuditPasswordChanged,
+			Method:    models.AuditMethodCurrentPassword,
+			CreatedAt: now,
+		},
+	})
+	require.NoError(t, err)
+
+	user, err := st.UserByID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(user.PassHash))
+
+	kept, err = st.Session(ctx, []byte("kept"))
+	require.NoError(t, err)
+	assert.False(t, kept.Revoked)
+
+	other, err := st.Session(ctx, []byte("other"))
+	require.NoError(t, err)
+	assert.True(t, other.Revoked)
+
+	_, err = st.TakePasswordReset(ctx, []byte("reset"))
+	assert.ErrorIs(t, err, storage.ErrPasswordResetNotFound)
+
+	events, err := st.AuditEvents(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditPasswordChanged, events[0].Action)
+	assert.Equal(t, models.AuditMethodCurrentPassword, events[0].Method)
+	assert.Equal(t, now.UnixNano(), events[0].CreatedAt.UnixNano())
+}
+
+func TestChangePassword_UnknownUser(t *testing.T) {
+	ctx := context.Background()
+	st := newStorage(t)
+
+	err := st.ChangePassword(ctx, models.PasswordChange{
+		UserID:   42,
+		PassHash: []byte("new"),
+		Event:    models.AuditEvent{Action: models.AuditPasswordChanged, CreatedAt: time.Now()},
+	})
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	// The transaction is rolled back, so no event is left behind.
+	events, err := st.AuditEvents(ctx, 42)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func newStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sso.db")
+	_, err := schema.UpSQLite(path)
+	require.NoError(t, err)
+
+	masterKey, err := envelope.NewDataKey()
+	require.NoError(t, err)
+	keyring, err := envelope.NewKeyring(masterKey)
+	require.NoError(t, err)
+
+	st, err := sqlite.New(path, keyring)
+	require.NoError(t, err)
+	t.Cleanup(func() { st.Close() })
+
+	return st
+}
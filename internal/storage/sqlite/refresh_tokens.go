@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+var saveRefreshTokenStmt = prepare(`INSERT INTO refresh_tokens (token_hash, family_id, user_id, app_id, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)`)
+
+func (s *Storage) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	const op = "storage.sqlite.SaveRefreshToken"
+
+	_, err := s.stmts[saveRefreshTokenStmt].ExecContext(ctx, token.TokenHash, token.FamilyID, token.UserID, token.AppID,
+		unixNano(token.ExpiresAt), unixNano(time.Now()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var refreshTokenStmt = prepare(`SELECT id, token_hash, family_id, user_id, app_id, expires_at,
+	rotated_at IS NOT NULL, revoked_at IS NOT NULL
+	FROM refresh_tokens WHERE token_hash = $1`)
+
+func (s *Storage) RefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error) {
+	const op = "storage.sqlite.RefreshToken"
+
+	var (
+		token     models.RefreshToken
+		expiresAt sql.NullInt64
+	)
+	err := s.stmts[refreshTokenStmt].QueryRowContext(ctx, tokenHash).Scan(
+		&token.ID, &token.TokenHash, &token.FamilyID, &token.UserID, &token.AppID, &expiresAt,
+		&token.Rotated, &token.Revoked,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+		}
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	token.ExpiresAt = fromUnixNano(expiresAt)
+
+	return token, nil
+}
+
+// RotateRefreshToken marks the token with oldID as rotated and saves next in
+// the same transaction. storage.ErrRefreshTokenNotRotatable is returned when
+// the old token has already been rotated or revoked, e.g. by a concurrent request.
+func (s *Storage) RotateRefreshToken(ctx context.Context, oldID int64, next models.RefreshToken) error {
+	const op = "storage.sqlite.RotateRefreshToken"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	now := unixNano(time.Now())
+
+	res, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET rotated_at = $2
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL`, oldID, now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotRotatable)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO refresh_tokens (token_hash, family_id, user_id, app_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		next.TokenHash, next.FamilyID, next.UserID, next.AppID, unixNano(next.ExpiresAt), now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var revokeRefreshTokenFamilyStmt = prepare("UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL")
+
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	const op = "storage.sqlite.RevokeRefreshTokenFamily"
+
+	_, err := s.stmts[revokeRefreshTokenFamilyStmt].ExecContext(ctx, familyID, unixNano(time.Now()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
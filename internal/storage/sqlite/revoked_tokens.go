@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var purgeRevokedTokensStmt = prepare("DELETE FROM revoked_tokens WHERE expires_at < $1")
+
+var revokeTokenStmt = prepare(`INSERT INTO revoked_tokens (jti, expires_at, revoked_at) VALUES ($1, $2, $3)
+	ON CONFLICT (jti) DO NOTHING`)
+
+// RevokeToken adds jti to the denylist until expiresAt. Entries whose token
+// has already expired are purged on the way, so the table stays small.
+func (s *Storage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "storage.sqlite.RevokeToken"
+
+	now := unixNano(time.Now())
+
+	if _, err := s.stmts[purgeRevokedTokensStmt].ExecContext(ctx, now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err := s.stmts[revokeTokenStmt].ExecContext(ctx, jti, unixNano(expiresAt), now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var isTokenRevokedStmt = prepare("SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at >= $2)")
+
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	const op = "storage.sqlite.IsTokenRevoked"
+
+	var revoked bool
+	if err := s.stmts[isTokenRevokedStmt].QueryRowContext(ctx, jti, unixNano(time.Now())).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,172 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/storage"
+)
+
+// AssignRole gives userID the role in appID, creating the role if needed.
+func (s *Storage) AssignRole(ctx context.Context, userID int64, appID int64, role string) error {
+	const op = "storage.sqlite.AssignRole"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := userExists(ctx, tx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	roleID, err := upsertRole(ctx, tx, appID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		userID, roleID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var revokeRoleStmt = prepare(`DELETE FROM user_roles
+	WHERE user_id = $1 AND role_id IN (SELECT id FROM roles WHERE app_id = $2 AND name = $3)`)
+
+func (s *Storage) RevokeRole(ctx context.Context, userID int64, appID int64, role string) error {
+	const op = "storage.sqlite.RevokeRole"
+
+	res, err := s.stmts[revokeRoleStmt].ExecContext(ctx, userID, appID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	return nil
+}
+
+// GrantPermission adds permission to role in appID, creating both if needed.
+func (s *Storage) GrantPermission(ctx context.Context, appID int64, role string, permission string) error {
+	const op = "storage.sqlite.GrantPermission"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	roleID, err := upsertRole(ctx, tx, appID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var permissionID int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO permissions (app_id, name) VALUES ($1, $2)
+		ON CONFLICT (app_id, name) DO UPDATE SET name = excluded.name
+		RETURNING id`, appID, permission).Scan(&permissionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		roleID, permissionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var hasPermissionStmt = prepare(`SELECT EXISTS(
+	SELECT 1 FROM user_roles ur
+	JOIN roles r ON r.id = ur.role_id
+	JOIN role_permissions rp ON rp.role_id = r.id
+	JOIN permissions p ON p.id = rp.permission_id
+	WHERE ur.user_id = $1 AND r.app_id = $2 AND p.name = $3)`)
+
+func (s *Storage) HasPermission(ctx context.Context, userID int64, appID int64, permission string) (bool, error) {
+	const op = "storage.sqlite.HasPermission"
+
+	var has bool
+	if err := s.stmts[hasPermissionStmt].QueryRowContext(ctx, userID, appID, permission).Scan(&has); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return has, nil
+}
+
+var userRolesStmt = prepare(`SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+	WHERE ur.user_id = $1 AND r.app_id = $2 ORDER BY r.name`)
+
+// UserRoles returns the names of the roles userID has in appID.
+func (s *Storage) UserRoles(ctx context.Context, userID int64, appID int64) ([]string, error) {
+	const op = "storage.sqlite.UserRoles"
+
+	rows, err := s.stmts[userRolesStmt].QueryContext(ctx, userID, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+func userExists(ctx context.Context, tx *sql.Tx, userID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1", userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrUserNotFound
+	}
+
+	return err
+}
+
+func upsertRole(ctx context.Context, tx *sql.Tx, appID int64, role string) (int64, error) {
+	var appExists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM apps WHERE id = $1)", appID).Scan(&appExists); err != nil {
+		return 0, err
+	}
+	if !appExists {
+		return 0, storage.ErrAppNotFound
+	}
+
+	var roleID int64
+	err := tx.QueryRowContext(ctx, `INSERT INTO roles (app_id, name) VALUES ($1, $2)
+		ON CONFLICT (app_id, name) DO UPDATE SET name = excluded.name
+		RETURNING id`, appID, role).Scan(&roleID)
+
+	return roleID, err
+}
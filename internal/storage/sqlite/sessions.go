@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"time"
+)
+
+var saveSessionStmt = prepare(`INSERT INTO sessions (token_hash, user_id, created_at, last_seen_at, expires_at)
+	VALUES ($1, $2, $3, $3, $4)`)
+
+func (s *Storage) SaveSession(ctx context.Context, session models.Session) error {
+	const op = "storage.sqlite.SaveSession"
+
+	_, err := s.stmts[saveSessionStmt].ExecContext(ctx, session.TokenHash, session.UserID,
+		unixNano(session.CreatedAt), unixNano(session.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+var sessionStmt = prepare(`SELECT id, token_hash, user_id, created_at, last_seen_at, expires_at, revoked_at IS NOT NULL
+	FROM sessions WHERE token_hash = $1`)
+
+func (s *Storage) Session(ctx context.Context, tokenHash []byte) (models.Session, error) {
+	const op = "storage.sqlite.Session"
+
+	var (
+		session                          models.Session
+		createdAt, lastSeenAt, expiresAt sql.NullInt64
+	)
+	err := s.stmts[sessionStmt].QueryRowContext(ctx, tokenHash).Scan(
+		&session.ID, &session.TokenHash, &session.UserID,
+		&createdAt, &lastSeenAt, &expiresAt, &session.Revoked,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	session.CreatedAt = fromUnixNano(createdAt)
+	session.LastSeenAt = fromUnixNano(lastSeenAt)
+	session.ExpiresAt = fromUnixNano(expiresAt)
+
+	return session, nil
+}
+
+var touchSessionStmt = prepare(`UPDATE sessions SET last_seen_at = $3
+	WHERE id = $1 AND revoked_at IS NULL AND expires_at > $3 AND last_seen_at > $2`)
+
+// TouchSession records a use of the session. storage.ErrSessionNotFound is
+// returned if the session is revoked, expired or was last seen before idleSince.
+func (s *Storage) TouchSession(ctx context.Context, sessionID int64, idleSince time.Time) error {
+	const op = "storage.sqlite.TouchSession"
+
+	res, err := s.stmts[touchSessionStmt].ExecContext(ctx, sessionID, unixNano(idleSince), unixNano(time.Now()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+var revokeSessionStmt = prepare("UPDATE sessions SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL")
+
+func (s *Storage) RevokeSession(ctx context.Context, sessionID int64) error {
+	const op = "storage.sqlite.RevokeSession"
+
+	if _, err := s.stmts[revokeSessionStmt].ExecContext(ctx, sessionID, unixNano(time.Now())); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
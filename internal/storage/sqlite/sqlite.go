@@ -0,0 +1,205 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"net/url"
+	"sso/internal/domain/models"
+	"sso/internal/lib/envelope"
+	"sso/internal/storage"
+	"sync"
+	"time"
+)
+
+// openTimeout bounds how long New waits for the database to open.
+const openTimeout = 5 * time.Second
+
+var saveUserStmt = prepare("INSERT INTO users (email, pass_hash) VALUES ($1, $2) RETURNING id")
+
+// Storage keeps everything in a single SQLite database file. It is meant for
+// single-node deployments; several processes must not share the file.
+//
+// SQLite has no time type, so times are stored as Unix nanoseconds. Write
+// transactions take the database lock when they begin, which serializes them
+// the way row locks do in Postgres.
+type Storage struct {
+	db      *sql.DB
+	stmts   statements
+	keyring *envelope.Keyring
+
+	mu       sync.RWMutex
+	dataKeys dataKeys
+}
+
+// New opens the database file at path, creating it if needed. App secrets
+// and signing keys are encrypted with data keys wrapped by the master keys in
+// keyring.
+func New(path string, keyring *envelope.Keyring) (*Storage, error) {
+	const op = "storage.sqlite.New"
+
+	db, err := sql.Open("sqlite", dsn(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), openTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmts, err := prepareStatements(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{
+		db:       db,
+		stmts:    stmts,
+		keyring:  keyring,
+		dataKeys: dataKeys{keys: make(map[int64][]byte)},
+	}, nil
+}
+
+// dsn enables foreign keys and the write-ahead log, lets connections wait
+// for the lock instead of failing and makes transactions take the write lock
+// when they begin.
+func dsn(path string) string {
+	params := url.Values{
+		"_pragma": {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"},
+		"_txlock": {"immediate"},
+	}
+
+	return "file:" + path + "?" + params.Encode()
+}
+
+// Close closes the prepared statements and the database.
+func (s *Storage) Close() error {
+	return errors.Join(s.stmts.close(), s.db.Close())
+}
+
+// SaveUser creates a user and returns its ID. storage.ErrUserExists is
+// returned if the email is already taken.
+func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (int64, error) {
+	const op = "storage.sqlite.SaveUser"
+
+	var uid int64
+	err := s.stmts[saveUserStmt].QueryRowContext(ctx, email, passHash).Scan(&uid)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return uid, nil
+}
+
+var userStmt = prepare("SELECT id, email, pass_hash, is_admin, disabled, email_verified FROM users WHERE email = $1")
+
+func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.sqlite.User"
+
+	var user models.User
+	err := s.stmts[userStmt].QueryRowContext(ctx, email).Scan(
+		&user.ID, &user.Email, &user.PassHash, &user.IsAdmin, &user.Disabled, &user.EmailVerified,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, storage.ErrUserNotFound
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+var userByIDStmt = prepare("SELECT id, email, pass_hash, is_admin, disabled, email_verified FROM users WHERE id = $1")
+
+func (s *Storage) UserByID(ctx context.Context, userID int64) (models.User, error) {
+	const op = "storage.sqlite.UserByID"
+
+	var user models.User
+	err := s.stmts[userByIDStmt].QueryRowContext(ctx, userID).Scan(
+		&user.ID, &user.Email, &user.PassHash, &user.IsAdmin, &user.Disabled, &user.EmailVerified,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+var isAdminStmt = prepare("SELECT is_admin FROM users WHERE id = $1")
+
+func (s *Storage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	const op = "storage.sqlite.IsAdmin"
+
+	var isAdmin bool
+	err := s.stmts[isAdminStmt].QueryRowContext(ctx, userID).Scan(&isAdmin)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return isAdmin, nil
+}
+
+var appStmt = prepare("SELECT " + appColumns + " FROM apps WHERE id = $1")
+
+func (s *Storage) App(ctx context.Context, appID int64) (models.App, error) {
+	const op = "storage.sqlite.App"
+
+	app, err := scanApp(s.stmts[appStmt].QueryRowContext(ctx, appID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app.Secrets, err = s.AppSecrets(ctx, appID)
+	if err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, secret := range app.Secrets {
+		if secret.State == models.AppSecretActive {
+			app.Secret = secret.Secret
+			break
+		}
+	}
+
+	return app, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// unixNano converts t to the stored form. The zero time is stored as NULL.
+func unixNano(t time.Time) sql.NullInt64 {
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: !t.IsZero()}
+}
+
+// fromUnixNano converts a stored time back. NULL becomes the zero time.
+func fromUnixNano(n sql.NullInt64) time.Time {
+	if !n.Valid {
+		return time.Time{}
+	}
+
+	return time.Unix(0, n.Int64)
+}
@@ -3,7 +3,24 @@ package storage
 import "errors"
 
 var (
-	ErrUserExists   = errors.New("user already exists")
-	ErrUserNotFound = errors.New("user not found")
-	// ErrAppNotFound  = errors.New("app not found")
+	ErrUserExists                = errors.New("user already exists")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrAppNotFound               = errors.New("app not found")
+	ErrAppExists                 = errors.New("app already exists")
+	ErrRefreshTokenNotFound      = errors.New("refresh token not found")
+	ErrRefreshTokenNotRotatable  = errors.New("refresh token already rotated or revoked")
+	ErrSigningKeyNotFound        = errors.New("signing key not found")
+	ErrSessionNotFound           = errors.New("session not found")
+	ErrRoleNotFound              = errors.New("role not found")
+	ErrTOTPNotFound              = errors.New("totp not found")
+	ErrTOTPConfirmed             = errors.New("totp already confirmed")
+	ErrTOTPStepUsed              = errors.New("totp code already used")
+	ErrRecoveryCodeNotFound      = errors.New("recovery code not found")
+	ErrMFAChallengeNotFound      = errors.New("mfa challenge not found")
+	ErrPasskeyExists             = errors.New("passkey already registered")
+	ErrPasskeyNotFound           = errors.New("passkey not found")
+	ErrPasskeySignCount          = errors.New("passkey sign count did not increase")
+	ErrCeremonyNotFound          = errors.New("webauthn ceremony not found")
+	ErrEmailVerificationNotFound = errors.New("email verification not found")
+	ErrPasswordResetNotFound     = errors.New("password reset not found")
 )
@@ -0,0 +1,15 @@
+ALTER TABLE apps
+    ADD COLUMN secret TEXT;
+
+UPDATE apps
+SET secret = (SELECT s.secret
+              FROM app_secrets s
+              WHERE s.app_id = apps.id
+              ORDER BY s.state = 'active' DESC, s.created_at DESC
+              LIMIT 1);
+
+ALTER TABLE apps
+    ALTER COLUMN secret SET NOT NULL,
+    ADD CONSTRAINT apps_secret_key UNIQUE (secret);
+
+DROP TABLE IF EXISTS app_secrets;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS app_secrets
+(
+    id         BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    app_id     BIGINT      NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    secret     TEXT        NOT NULL UNIQUE,
+    state      TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_app_secrets_app_id ON app_secrets (app_id);
+
+INSERT INTO app_secrets (app_id, secret, state)
+SELECT id, secret, 'active'
+FROM apps;
+
+ALTER TABLE apps DROP COLUMN secret;
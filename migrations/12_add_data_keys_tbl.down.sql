@@ -0,0 +1,19 @@
+DO
+$$
+    BEGIN
+        IF EXISTS (SELECT 1 FROM app_secrets WHERE data_key_id IS NOT NULL)
+            OR EXISTS (SELECT 1 FROM signing_keys WHERE data_key_id IS NOT NULL) THEN
+            RAISE EXCEPTION 'encrypted app secrets or signing keys exist and cannot be decrypted by a migration';
+        END IF;
+    END
+$$;
+
+ALTER TABLE signing_keys
+    DROP COLUMN data_key_id;
+
+ALTER TABLE app_secrets
+    DROP COLUMN data_key_id,
+    ALTER COLUMN secret TYPE TEXT USING convert_from(secret, 'UTF8'),
+    ADD CONSTRAINT app_secrets_secret_key UNIQUE (secret);
+
+DROP TABLE IF EXISTS data_keys;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id         BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret          BYTEA       NOT NULL,
+    data_key_id     BIGINT      NOT NULL REFERENCES data_keys (id),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    confirmed_at    TIMESTAMPTZ,
+    last_step       BIGINT      NOT NULL DEFAULT 0,
+    -- failures counts the wrong second factors in a row, across logins.
+    failures        INTEGER     NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    user_id   BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash BYTEA  NOT NULL,
+    used_at   TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges
+(
+    token_hash BYTEA PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id     BIGINT      NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    attempts   INTEGER     NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user_id ON mfa_challenges (user_id);
@@ -0,0 +1,8 @@
+ALTER TABLE signing_keys
+    ADD COLUMN state        TEXT NOT NULL DEFAULT 'active',
+    ADD COLUMN activated_at TIMESTAMPTZ,
+    ADD COLUMN retire_at    TIMESTAMPTZ;
+
+UPDATE signing_keys SET activated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_signing_keys_state ON signing_keys (state);
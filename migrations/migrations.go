@@ -0,0 +1,16 @@
+// Package migrations embeds the schema migrations so that the binaries can
+// apply them without the SQL files being deployed alongside.
+package migrations
+
+import "embed"
+
+// FS holds the Postgres migrations in PostgresDir and the SQLite ones in
+// SQLiteDir.
+//
+//go:embed *.sql sqlite/*.sql
+var FS embed.FS
+
+const (
+	PostgresDir = "."
+	SQLiteDir   = "sqlite"
+)
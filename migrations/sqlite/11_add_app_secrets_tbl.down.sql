@@ -0,0 +1,11 @@
+ALTER TABLE apps
+    ADD COLUMN secret TEXT NOT NULL DEFAULT '';
+
+UPDATE apps
+SET secret = (SELECT CAST(s.secret AS TEXT)
+              FROM app_secrets s
+              WHERE s.app_id = apps.id
+              ORDER BY s.state = 'active' DESC, s.created_at DESC
+              LIMIT 1);
+
+DROP TABLE IF EXISTS app_secrets;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS app_secrets
+(
+    id         INTEGER PRIMARY KEY,
+    app_id     INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    secret     BLOB    NOT NULL,
+    state      TEXT    NOT NULL,
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_app_secrets_app_id ON app_secrets (app_id);
+
+INSERT INTO app_secrets (app_id, secret, state, created_at)
+SELECT id, secret, 'active', CAST(unixepoch('subsec') * 1000000000 AS INTEGER)
+FROM apps;
+
+ALTER TABLE apps DROP COLUMN secret;
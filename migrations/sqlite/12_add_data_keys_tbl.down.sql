@@ -0,0 +1,8 @@
+-- Encrypted values cannot be decrypted by a migration, so they are lost.
+DELETE FROM app_secrets WHERE data_key_id IS NOT NULL;
+DELETE FROM signing_keys WHERE data_key_id IS NOT NULL;
+
+ALTER TABLE signing_keys DROP COLUMN data_key_id;
+ALTER TABLE app_secrets DROP COLUMN data_key_id;
+
+DROP TABLE IF EXISTS data_keys;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS data_keys
+(
+    id            INTEGER PRIMARY KEY,
+    wrapped_key   BLOB    NOT NULL,
+    master_key_id TEXT    NOT NULL,
+    created_at    INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_data_keys_master_key_id ON data_keys (master_key_id);
+
+-- Rows without a data key are still plaintext; the rekey command encrypts
+-- them. The columns carry no foreign key, as SQLite could not drop them
+-- again in the down migration.
+ALTER TABLE app_secrets
+    ADD COLUMN data_key_id INTEGER;
+
+ALTER TABLE signing_keys
+    ADD COLUMN data_key_id INTEGER;
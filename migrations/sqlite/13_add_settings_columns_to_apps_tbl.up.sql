@@ -0,0 +1,5 @@
+-- scopes is space-separated, as in OAuth 2.0.
+ALTER TABLE apps
+    ADD COLUMN token_ttl_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE apps
+    ADD COLUMN scopes TEXT NOT NULL DEFAULT '';
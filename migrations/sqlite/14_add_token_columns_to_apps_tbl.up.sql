@@ -0,0 +1,7 @@
+-- claims is a space-separated list; NULL means the default claims.
+ALTER TABLE apps
+    ADD COLUMN issuer TEXT NOT NULL DEFAULT '';
+ALTER TABLE apps
+    ADD COLUMN audience TEXT NOT NULL DEFAULT '';
+ALTER TABLE apps
+    ADD COLUMN claims TEXT;
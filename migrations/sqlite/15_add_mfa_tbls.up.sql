@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id         INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret          BLOB    NOT NULL,
+    data_key_id     INTEGER NOT NULL REFERENCES data_keys (id),
+    created_at      INTEGER NOT NULL,
+    confirmed_at    INTEGER,
+    last_step       INTEGER NOT NULL DEFAULT 0,
+    -- failures counts the wrong second factors in a row, across logins.
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failure_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    user_id   INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash BLOB    NOT NULL,
+    used_at   INTEGER,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges
+(
+    token_hash BLOB PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id     INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    expires_at INTEGER NOT NULL,
+    attempts   INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user_id ON mfa_challenges (user_id);
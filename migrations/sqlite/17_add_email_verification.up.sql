@@ -0,0 +1,18 @@
+-- Users that exist before verification is introduced count as verified, so
+-- that apps which start to require a verified email do not lock them out.
+-- Users registered from now on start unverified.
+ALTER TABLE users
+    ADD COLUMN email_verified INTEGER NOT NULL DEFAULT 0;
+UPDATE users SET email_verified = 1;
+
+ALTER TABLE apps
+    ADD COLUMN require_verified_email INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS email_verifications
+(
+    token_hash BLOB PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email      TEXT    NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications (user_id);
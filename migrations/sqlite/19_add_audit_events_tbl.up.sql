@@ -0,0 +1,10 @@
+-- Events outlive the users they are about, so user_id is not a foreign key.
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id         INTEGER PRIMARY KEY,
+    user_id    INTEGER NOT NULL,
+    action     TEXT    NOT NULL,
+    method     TEXT    NOT NULL,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events (user_id);
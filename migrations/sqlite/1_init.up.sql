@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS users
+(
+    id        INTEGER PRIMARY KEY,
+    email     TEXT NOT NULL UNIQUE,
+    pass_hash BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
+
+-- Unlike in Postgres, secret is not UNIQUE: SQLite cannot drop a UNIQUE
+-- column, and migration 11 moves secrets to their own table.
+CREATE TABLE IF NOT EXISTS apps
+(
+    id     INTEGER PRIMARY KEY,
+    name   TEXT NOT NULL UNIQUE,
+    secret TEXT NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_signing_keys_state;
+ALTER TABLE signing_keys DROP COLUMN retire_at;
+ALTER TABLE signing_keys DROP COLUMN activated_at;
+ALTER TABLE signing_keys DROP COLUMN state;
@@ -0,0 +1,10 @@
+ALTER TABLE signing_keys
+    ADD COLUMN state TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE signing_keys
+    ADD COLUMN activated_at INTEGER;
+ALTER TABLE signing_keys
+    ADD COLUMN retire_at INTEGER;
+
+UPDATE signing_keys SET activated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_signing_keys_state ON signing_keys (state);
@@ -0,0 +1,445 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"sso/tests/suit"
+	"testing"
+)
+
+// The admin the test migrations and the memory seed create.
+const (
+	adminEmail    = "admin@sso.test"
+	adminPassword = "admin-password"
+)
+
+func TestAdmin_RequiresAdminToken(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	_, err := st.AdminClient.ListApps(ctx, &ssov1.ListAppsRequest{})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "bearer token required")
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+resLogin.GetToken())
+
+	_, err = st.AdminClient.ListApps(userCtx, &ssov1.ListAppsRequest{})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "permission denied")
+
+	_, err = st.AdminClient.SetAdmin(userCtx, &ssov1.SetAdminRequest{
+		UserId:  resReg.GetUserId(),
+		IsAdmin: true,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "permission denied")
+
+	_, err = st.AdminClient.RotateAppSecret(userCtx, &ssov1.RotateAppSecretRequest{
+		AppId: appID,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "permission denied")
+
+	_, err = st.AdminClient.RotateAppSecret(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.RotateAppSecretRequest{
+		AppId: appID,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "bearer token required")
+
+	_, err = st.AdminClient.ListApps(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+gofakeit.UUID()), &ssov1.ListAppsRequest{})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid token")
+}
+
+func TestAdmin_Apps(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	adminCtx := loginAdmin(ctx, t, st)
+
+	app := int32(gofakeit.Number(1000, 1<<30))
+	name := gofakeit.UUID()
+
+	resCreate, err := st.AdminClient.CreateApp(adminCtx, &ssov1.CreateAppRequest{
+		AppId: app,
+		Name:  name,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, app, resCreate.GetApp().GetId())
+	assert.Equal(t, name, resCreate.GetApp().GetName())
+
+	// Without a secret in the request one is generated.
+	secret := resCreate.GetSecret()
+	require.NotEmpty(t, secret)
+
+	_, err = st.AdminClient.CreateApp(adminCtx, &ssov1.CreateAppRequest{
+		AppId: app,
+		Name:  gofakeit.UUID(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// The generated secret authenticates the app.
+	_, err = st.AuthClient.Introspect(suit.WithAppCredentials(ctx, int(app), secret), &ssov1.IntrospectRequest{
+		Token: gofakeit.UUID(),
+	})
+	require.NoError(t, err)
+
+	listedName := func() (string, bool) {
+		resList, err := st.AdminClient.ListApps(adminCtx, &ssov1.ListAppsRequest{})
+		require.NoError(t, err)
+
+		for _, listed := range resList.GetApps() {
+			if listed.GetId() == app {
+				return listed.GetName(), true
+			}
+		}
+		return "", false
+	}
+
+	listed, ok := listedName()
+	require.True(t, ok)
+	assert.Equal(t, name, listed)
+
+	newName := gofakeit.UUID()
+
+	_, err = st.AdminClient.UpdateApp(adminCtx, &ssov1.UpdateAppRequest{
+		AppId: app,
+		Name:  newName,
+	})
+	require.NoError(t, err)
+
+	listed, ok = listedName()
+	require.True(t, ok)
+	assert.Equal(t, newName, listed)
+
+	_, err = st.AdminClient.DeleteApp(adminCtx, &ssov1.DeleteAppRequest{AppId: app})
+	require.NoError(t, err)
+
+	_, ok = listedName()
+	assert.False(t, ok)
+
+	_, err = st.AdminClient.DeleteApp(adminCtx, &ssov1.DeleteAppRequest{AppId: app})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestAdmin_Users(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	adminCtx := loginAdmin(ctx, t, st)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	userID := resReg.GetUserId()
+
+	getUser := func() *ssov1.User {
+		resGet, err := st.AdminClient.GetUser(adminCtx, &ssov1.GetUserRequest{UserId: userID})
+		require.NoError(t, err)
+
+		return resGet.GetUser()
+	}
+
+	login := func() error {
+		_, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Email:    email,
+			Password: password,
+			AppId:    appID,
+		})
+		return err
+	}
+
+	user := getUser()
+	assert.Equal(t, userID, user.GetId())
+	assert.Equal(t, email, user.GetEmail())
+	assert.False(t, user.GetIsAdmin())
+	assert.False(t, user.GetDisabled())
+
+	// Users are listed by ID, so the page after the previous ID starts with
+	// the user.
+	resList, err := st.AdminClient.ListUsers(adminCtx, &ssov1.ListUsersRequest{
+		AfterId:  userID - 1,
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, resList.GetUsers(), 1)
+	assert.Equal(t, userID, resList.GetUsers()[0].GetId())
+
+	_, err = st.AdminClient.SetAdmin(adminCtx, &ssov1.SetAdminRequest{
+		UserId:  userID,
+		IsAdmin: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, getUser().GetIsAdmin())
+
+	_, err = st.AdminClient.DisableUser(adminCtx, &ssov1.DisableUserRequest{
+		UserId:   userID,
+		Disabled: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, getUser().GetDisabled())
+
+	err = login()
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AdminClient.DisableUser(adminCtx, &ssov1.DisableUserRequest{
+		UserId:   userID,
+		Disabled: false,
+	})
+	require.NoError(t, err)
+	assert.False(t, getUser().GetDisabled())
+	require.NoError(t, login())
+
+	_, err = st.AdminClient.DeleteUser(adminCtx, &ssov1.DeleteUserRequest{UserId: userID})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.GetUser(adminCtx, &ssov1.GetUserRequest{UserId: userID})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	err = login()
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAdmin_DisabledAdminLosesAccess(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	adminCtx := loginAdmin(ctx, t, st)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.SetAdmin(adminCtx, &ssov1.SetAdminRequest{
+		UserId:  resReg.GetUserId(),
+		IsAdmin: true,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	otherCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+resLogin.GetToken())
+
+	_, err = st.AdminClient.ListApps(otherCtx, &ssov1.ListAppsRequest{})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.DisableUser(adminCtx, &ssov1.DisableUserRequest{
+		UserId:   resReg.GetUserId(),
+		Disabled: true,
+	})
+	require.NoError(t, err)
+
+	// The access token stops working at once, not when it expires.
+	_, err = st.AdminClient.ListApps(otherCtx, &ssov1.ListAppsRequest{})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid token")
+
+	resIntrospect, err := st.AuthClient.Introspect(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.IntrospectRequest{
+		Token: resLogin.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.False(t, resIntrospect.GetActive())
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAdmin_RotateAppSecret(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	if st.Cfg.Signing.Algorithm != "HS256" {
+		t.Skip("app secrets sign tokens only with HS256")
+	}
+
+	adminCtx := loginAdmin(ctx, t, st)
+	app, oldSecret := createApp(adminCtx, t, st)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	login := func() string {
+		resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Email:    email,
+			Password: password,
+			AppId:    app,
+		})
+		require.NoError(t, err)
+
+		return resLogin.GetToken()
+	}
+
+	oldToken := login()
+	assertSignedWith(t, oldToken, oldSecret)
+
+	resRotate, err := st.AdminClient.RotateAppSecret(adminCtx, &ssov1.RotateAppSecretRequest{
+		AppId:              app,
+		GracePeriodSeconds: 3600,
+	})
+	require.NoError(t, err)
+
+	newSecret := resRotate.GetSecret()
+	require.NotEmpty(t, newSecret)
+	require.NotEqual(t, oldSecret, newSecret)
+
+	oldCtx := suit.WithAppCredentials(ctx, int(app), oldSecret)
+	newCtx := suit.WithAppCredentials(ctx, int(app), newSecret)
+
+	// During the grace period both secrets authenticate the app and tokens
+	// signed with the old one stay valid.
+	for _, credsCtx := range []context.Context{oldCtx, newCtx} {
+		resIntrospect, err := st.AuthClient.Introspect(credsCtx, &ssov1.IntrospectRequest{
+			Token: oldToken,
+		})
+		require.NoError(t, err)
+		assert.True(t, resIntrospect.GetActive())
+	}
+
+	newToken := login()
+	assertSignedWith(t, newToken, newSecret)
+	assertNotSignedWith(t, newToken, oldSecret)
+
+	_, err = st.AdminClient.RetireAppSecrets(adminCtx, &ssov1.RetireAppSecretsRequest{
+		AppId: app,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Introspect(oldCtx, &ssov1.IntrospectRequest{
+		Token: newToken,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid app credentials")
+
+	resIntrospect, err := st.AuthClient.Introspect(newCtx, &ssov1.IntrospectRequest{
+		Token: oldToken,
+	})
+	require.NoError(t, err)
+	assert.False(t, resIntrospect.GetActive())
+
+	resIntrospect, err = st.AuthClient.Introspect(newCtx, &ssov1.IntrospectRequest{
+		Token: newToken,
+	})
+	require.NoError(t, err)
+	assert.True(t, resIntrospect.GetActive())
+}
+
+func TestAdmin_AppSecrets_UnknownApp(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	adminCtx := loginAdmin(ctx, t, st)
+
+	// createApp picks IDs up to 1<<30, so this one is never taken.
+	app := int32(gofakeit.Number(1<<30+1, 1<<31-1))
+
+	_, err := st.AdminClient.RotateAppSecret(adminCtx, &ssov1.RotateAppSecretRequest{AppId: app})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.AdminClient.RetireAppSecrets(adminCtx, &ssov1.RetireAppSecretsRequest{AppId: app})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// createApp creates an app with a random ID and returns the ID and the
+// secret. The app is deleted when the test finishes.
+func createApp(adminCtx context.Context, t *testing.T, st *suit.Suit) (int32, string) {
+	t.Helper()
+
+	app := int32(gofakeit.Number(1000, 1<<30))
+
+	resCreate, err := st.AdminClient.CreateApp(adminCtx, &ssov1.CreateAppRequest{
+		AppId:  app,
+		Name:   gofakeit.UUID(),
+		Secret: gofakeit.Password(true, true, true, false, false, 32),
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, err := st.AdminClient.DeleteApp(adminCtx, &ssov1.DeleteAppRequest{AppId: app})
+		require.NoError(t, err)
+	})
+
+	return app, resCreate.GetSecret()
+}
+
+func assertSignedWith(t *testing.T, token, secret string) {
+	t.Helper()
+
+	_, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	assert.NoError(t, err)
+}
+
+func assertNotSignedWith(t *testing.T, token, secret string) {
+	t.Helper()
+
+	_, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+// loginAdmin returns ctx with the access token of the test admin attached.
+func loginAdmin(ctx context.Context, t *testing.T, st *suit.Suit) context.Context {
+	t.Helper()
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    adminEmail,
+		Password: adminPassword,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+resLogin.GetToken())
+}
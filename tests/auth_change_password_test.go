@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/tests/suit"
+	"testing"
+)
+
+func TestChangePassword_CurrentPassword_KeepSession(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, password, _ := registerAndLogin(ctx, t, st)
+
+	current, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	other, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	newPassword := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err = st.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:           current.GetToken(),
+		SessionToken:    current.GetSessionToken(),
+		CurrentPassword: password,
+		NewPassword:     newPassword,
+		KeepSession:     true,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: newPassword,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	// The current session survives, the other one does not.
+	_, err = st.AuthClient.ExchangeSession(ctx, &ssov1.ExchangeSessionRequest{
+		SessionToken: current.GetSessionToken(),
+		AppId:        appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ExchangeSession(ctx, &ssov1.ExchangeSessionRequest{
+		SessionToken: other.GetSessionToken(),
+		AppId:        appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: other.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestChangePassword_RecentLogin(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, password, _ := registerAndLogin(ctx, t, st)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	newPassword := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	// A session that was just started stands in for the current password.
+	_, err = st.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:        resLogin.GetToken(),
+		SessionToken: resLogin.GetSessionToken(),
+		NewPassword:  newPassword,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: newPassword,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	// Without KeepSession the session used for the change ends too.
+	_, err = st.AuthClient.ExchangeSession(ctx, &ssov1.ExchangeSessionRequest{
+		SessionToken: resLogin.GetSessionToken(),
+		AppId:        appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestChangePassword_FailCases(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, password, accessToken := registerAndLogin(ctx, t, st)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	newPassword := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	tests := []struct {
+		name        string
+		req         *ssov1.ChangePasswordRequest
+		expectedErr codes.Code
+	}{
+		{
+			name: "Empty token",
+			req: &ssov1.ChangePasswordRequest{
+				CurrentPassword: password,
+				NewPassword:     newPassword,
+			},
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name: "Empty new password",
+			req: &ssov1.ChangePasswordRequest{
+				Token:           accessToken,
+				CurrentPassword: password,
+			},
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name: "Keep session without session",
+			req: &ssov1.ChangePasswordRequest{
+				Token:           accessToken,
+				CurrentPassword: password,
+				NewPassword:     newPassword,
+				KeepSession:     true,
+			},
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name: "Invalid token",
+			req: &ssov1.ChangePasswordRequest{
+				Token:           "invalid",
+				CurrentPassword: password,
+				NewPassword:     newPassword,
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+		{
+			name: "Wrong current password",
+			req: &ssov1.ChangePasswordRequest{
+				Token:           accessToken,
+				CurrentPassword: password + "wrong",
+				NewPassword:     newPassword,
+			},
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name: "Neither current password nor session",
+			req: &ssov1.ChangePasswordRequest{
+				Token:       accessToken,
+				NewPassword: newPassword,
+			},
+			expectedErr: codes.PermissionDenied,
+		},
+		{
+			name: "Unknown session",
+			req: &ssov1.ChangePasswordRequest{
+				Token:        resLogin.GetToken(),
+				SessionToken: gofakeit.UUID(),
+				NewPassword:  newPassword,
+			},
+			expectedErr: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.ChangePassword(ctx, tt.req)
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedErr, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"sso/tests/suit"
+	"testing"
+)
+
+func TestLogout_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Logout(ctx, &ssov1.LogoutRequest{
+		Token:        resLogin.GetToken(),
+		RefreshToken: resLogin.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+
+	tokenParsed, err := jwt.Parse(resLogin.GetToken(), tokenKey(ctx, t, st, appSecret))
+	require.NoError(t, err)
+
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+
+	resRevoked, err := st.AuthClient.IsTokenRevoked(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.IsTokenRevokedRequest{
+		TokenId: claims["jti"].(string),
+	})
+	require.NoError(t, err)
+	assert.True(t, resRevoked.GetRevoked())
+
+	_, err = st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: resLogin.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid refresh token")
+
+	_, err = st.AuthClient.Logout(ctx, &ssov1.LogoutRequest{
+		Token: resLogin.GetToken(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid token")
+}
+
+func TestRevokeToken_RequiresAdmin(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RevokeToken(ctx, &ssov1.RevokeTokenRequest{
+		Token: resLogin.GetToken(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "bearer token required")
+
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+resLogin.GetToken())
+
+	_, err = st.AuthClient.RevokeToken(userCtx, &ssov1.RevokeTokenRequest{
+		Token: resLogin.GetToken(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "permission denied")
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"encoding/base32"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/lib/totp"
+	"sso/tests/suit"
+	"testing"
+	"time"
+)
+
+func TestMFA_TOTP_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, password, secret, _ := enableTOTP(ctx, t, st)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	assert.True(t, resLogin.GetMfaRequired())
+	assert.Empty(t, resLogin.GetToken())
+	require.NotEmpty(t, resLogin.GetMfaToken())
+
+	// The code used to confirm the authenticator cannot be replayed, so use
+	// the one of the next time step.
+	code := totp.Code(secret, totp.Step(time.Now())+1)
+
+	resVerify, err := st.AuthClient.VerifyMFA(ctx, &ssov1.VerifyMFARequest{
+		MfaToken: resLogin.GetMfaToken(),
+		Code:     code,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resVerify.GetToken())
+	assert.NotEmpty(t, resVerify.GetRefreshToken())
+	assert.NotEmpty(t, resVerify.GetSessionToken())
+
+	_, err = st.AuthClient.VerifyMFA(ctx, &ssov1.VerifyMFARequest{
+		MfaToken: resLogin.GetMfaToken(),
+		Code:     code,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestMFA_RecoveryCode(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, password, _, recoveryCodes := enableTOTP(ctx, t, st)
+	require.NotEmpty(t, recoveryCodes)
+
+	login := func() string {
+		resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Email:    email,
+			Password: password,
+			AppId:    appID,
+		})
+		require.NoError(t, err)
+		require.True(t, resLogin.GetMfaRequired())
+
+		return resLogin.GetMfaToken()
+	}
+
+	resVerify, err := st.AuthClient.VerifyMFA(ctx, &ssov1.VerifyMFARequest{
+		MfaToken: login(),
+		Code:     recoveryCodes[0],
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resVerify.GetToken())
+
+	_, err = st.AuthClient.VerifyMFA(ctx, &ssov1.VerifyMFARequest{
+		MfaToken: login(),
+		Code:     recoveryCodes[0],
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMFA_ConfirmTOTP_InvalidCode(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	_, _, accessToken := registerAndLogin(ctx, t, st)
+
+	_, err := st.AuthClient.EnrollTOTP(ctx, &ssov1.EnrollTOTPRequest{Token: accessToken})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ConfirmTOTP(ctx, &ssov1.ConfirmTOTPRequest{
+		Token: accessToken,
+		Code:  "000000x",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMFA_VerifyMFA_Lockout(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, password, secret, _ := enableTOTP(ctx, t, st)
+
+	login := func() string {
+		resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Email:    email,
+			Password: password,
+			AppId:    appID,
+		})
+		require.NoError(t, err)
+		require.True(t, resLogin.GetMfaRequired())
+
+		return resLogin.GetMfaToken()
+	}
+
+	// Each wrong code gets a login of its own, so that the failures add up
+	// across logins rather than use up a single challenge.
+	for range st.Cfg.MFA.MaxFailures {
+		_, err := st.AuthClient.VerifyMFA(ctx, &ssov1.VerifyMFARequest{
+			MfaToken: login(),
+			Code:     "wrong-code",
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	// Once locked, even the right code is refused.
+	_, err := st.AuthClient.VerifyMFA(ctx, &ssov1.VerifyMFARequest{
+		MfaToken: login(),
+		Code:     totp.Code(secret, totp.Step(time.Now())+1),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// enableTOTP registers a user, enrolls and confirms a TOTP authenticator for
+// them and returns their credentials, the TOTP secret and the recovery codes.
+func enableTOTP(ctx context.Context, t *testing.T, st *suit.Suit) (string, string, []byte, []string) {
+	t.Helper()
+
+	email, password, accessToken := registerAndLogin(ctx, t, st)
+
+	resEnroll, err := st.AuthClient.EnrollTOTP(ctx, &ssov1.EnrollTOTPRequest{Token: accessToken})
+	require.NoError(t, err)
+	assert.Contains(t, resEnroll.GetUri(), "otpauth://totp/")
+
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(resEnroll.GetSecret())
+	require.NoError(t, err)
+
+	resConfirm, err := st.AuthClient.ConfirmTOTP(ctx, &ssov1.ConfirmTOTPRequest{
+		Token: accessToken,
+		Code:  totp.Code(secret, totp.Step(time.Now())),
+	})
+	require.NoError(t, err)
+
+	return email, password, secret, resConfirm.GetRecoveryCodes()
+}
+
+func registerAndLogin(ctx context.Context, t *testing.T, st *suit.Suit) (string, string, string) {
+	t.Helper()
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	require.False(t, resLogin.GetMfaRequired())
+
+	return email, password, resLogin.GetToken()
+}
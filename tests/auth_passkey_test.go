@@ -0,0 +1,284 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/tests/suit"
+	"testing"
+)
+
+func TestPasskey_RegisterAndLogin(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, _, accessToken := registerAndLogin(ctx, t, st)
+	authenticator := newSoftAuthenticator(t, st)
+
+	registerPasskey(ctx, t, st, authenticator, accessToken)
+
+	resLogin := loginWithPasskey(ctx, t, st, authenticator, email)
+	assert.NotEmpty(t, resLogin.GetRefreshToken())
+	assert.NotEmpty(t, resLogin.GetSessionToken())
+
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(resLogin.GetToken(), claims)
+	require.NoError(t, err)
+	assert.Equal(t, email, claims["email"])
+
+	// The signature counter keeps increasing, so logging in again works.
+	resLogin = loginWithPasskey(ctx, t, st, authenticator, email)
+	assert.NotEmpty(t, resLogin.GetToken())
+}
+
+func TestPasskey_Login_SignCountRegression(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, _, accessToken := registerAndLogin(ctx, t, st)
+	authenticator := newSoftAuthenticator(t, st)
+
+	registerPasskey(ctx, t, st, authenticator, accessToken)
+	loginWithPasskey(ctx, t, st, authenticator, email)
+
+	// A clone of the authenticator reports a counter that was already used.
+	authenticator.signCount--
+
+	resBegin, err := st.AuthClient.BeginPasskeyLogin(ctx, &ssov1.BeginPasskeyLoginRequest{
+		Email: email,
+		AppId: appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.FinishPasskeyLogin(ctx, &ssov1.FinishPasskeyLoginRequest{
+		CeremonyToken: resBegin.GetCeremonyToken(),
+		Credential:    authenticator.get(t, resBegin.GetOptions()),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestPasskey_Login_CeremonyReuse(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, _, accessToken := registerAndLogin(ctx, t, st)
+	authenticator := newSoftAuthenticator(t, st)
+
+	registerPasskey(ctx, t, st, authenticator, accessToken)
+
+	resBegin, err := st.AuthClient.BeginPasskeyLogin(ctx, &ssov1.BeginPasskeyLoginRequest{
+		Email: email,
+		AppId: appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.FinishPasskeyLogin(ctx, &ssov1.FinishPasskeyLoginRequest{
+		CeremonyToken: resBegin.GetCeremonyToken(),
+		Credential:    authenticator.get(t, resBegin.GetOptions()),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.FinishPasskeyLogin(ctx, &ssov1.FinishPasskeyLoginRequest{
+		CeremonyToken: resBegin.GetCeremonyToken(),
+		Credential:    authenticator.get(t, resBegin.GetOptions()),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestPasskey_BeginLogin_NoPasskey(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, _, _ := registerAndLogin(ctx, t, st)
+
+	_, err := st.AuthClient.BeginPasskeyLogin(ctx, &ssov1.BeginPasskeyLoginRequest{
+		Email: email,
+		AppId: appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func registerPasskey(ctx context.Context, t *testing.T, st *suit.Suit, authenticator *softAuthenticator, accessToken string) {
+	t.Helper()
+
+	resBegin, err := st.AuthClient.BeginPasskeyRegistration(ctx, &ssov1.BeginPasskeyRegistrationRequest{
+		Token: accessToken,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.FinishPasskeyRegistration(ctx, &ssov1.FinishPasskeyRegistrationRequest{
+		Token:         accessToken,
+		CeremonyToken: resBegin.GetCeremonyToken(),
+		Credential:    authenticator.create(t, resBegin.GetOptions()),
+	})
+	require.NoError(t, err)
+}
+
+func loginWithPasskey(ctx context.Context, t *testing.T, st *suit.Suit, authenticator *softAuthenticator, email string) *ssov1.FinishPasskeyLoginResponse {
+	t.Helper()
+
+	resBegin, err := st.AuthClient.BeginPasskeyLogin(ctx, &ssov1.BeginPasskeyLoginRequest{
+		Email: email,
+		AppId: appID,
+	})
+	require.NoError(t, err)
+
+	resFinish, err := st.AuthClient.FinishPasskeyLogin(ctx, &ssov1.FinishPasskeyLoginRequest{
+		CeremonyToken: resBegin.GetCeremonyToken(),
+		Credential:    authenticator.get(t, resBegin.GetOptions()),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, resFinish.GetToken())
+
+	return resFinish
+}
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// softAuthenticator is a passkey authenticator in software. It holds a single
+// ES256 credential and answers the options of the server the way a browser
+// and a platform authenticator would together.
+type softAuthenticator struct {
+	rpID         string
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, st *suit.Suit) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softAuthenticator{
+		rpID:         st.Cfg.WebAuthn.RPID,
+		origin:       st.Cfg.WebAuthn.RPOrigins[0],
+		key:          key,
+		credentialID: credentialID,
+	}
+}
+
+// create answers the options of navigator.credentials.create with a new
+// credential and "none" attestation.
+func (a *softAuthenticator) create(t *testing.T, options string) string {
+	t.Helper()
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	authData := a.authData(flagUserPresent | flagUserVerified | flagAttested)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(a.clientData(t, "webauthn.create", options)),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+// get answers the options of navigator.credentials.get with an assertion
+// signed by the credential, advancing the signature counter.
+func (a *softAuthenticator) get(t *testing.T, options string) string {
+	t.Helper()
+
+	a.signCount++
+
+	authData := a.authData(flagUserPresent | flagUserVerified)
+	clientData := a.clientData(t, "webauthn.get", options)
+	clientDataHash := sha256.Sum256(clientData)
+
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+	})
+}
+
+func (a *softAuthenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+
+	authData := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, a.signCount)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, typ, options string) []byte {
+	t.Helper()
+
+	var parsed struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(options), &parsed))
+	require.NotEmpty(t, parsed.PublicKey.Challenge)
+
+	clientData, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": parsed.PublicKey.Challenge,
+		"origin":    a.origin,
+	})
+	require.NoError(t, err)
+
+	return clientData
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]string) string {
+	t.Helper()
+
+	credential, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+
+	return string(credential)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
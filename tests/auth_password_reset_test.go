@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/tests/suit"
+	"testing"
+)
+
+func TestResetPassword_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RequestPasswordReset(ctx, &ssov1.RequestPasswordResetRequest{Email: email})
+	require.NoError(t, err)
+
+	newPassword := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err = st.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+		Token:    emailedToken(t, st, email, "reset your password"),
+		Password: newPassword,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: newPassword,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	// Sessions and refresh tokens issued before the reset are revoked.
+	_, err = st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: resLogin.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.ExchangeSession(ctx, &ssov1.ExchangeSessionRequest{
+		SessionToken: resLogin.GetSessionToken(),
+		AppId:        appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestResetPassword_TokenReuse(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, _, _ := registerAndLogin(ctx, t, st)
+
+	_, err := st.AuthClient.RequestPasswordReset(ctx, &ssov1.RequestPasswordResetRequest{Email: email})
+	require.NoError(t, err)
+
+	token := emailedToken(t, st, email, "reset your password")
+
+	_, err = st.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+		Token:    token,
+		Password: gofakeit.Password(true, true, true, true, true, passDefaultLen),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+		Token:    token,
+		Password: gofakeit.Password(true, true, true, true, true, passDefaultLen),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestRequestPasswordReset_UnknownEmail(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	// The response is the same whether or not the email belongs to a user.
+	_, err := st.AuthClient.RequestPasswordReset(ctx, &ssov1.RequestPasswordResetRequest{
+		Email: gofakeit.Email(),
+	})
+	require.NoError(t, err)
+}
+
+func TestResetPassword_FailCases(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	tests := []struct {
+		name        string
+		token       string
+		password    string
+		expectedErr codes.Code
+	}{
+		{
+			name:        "Empty token",
+			token:       "",
+			password:    gofakeit.Password(true, true, true, true, true, passDefaultLen),
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name:        "Empty password",
+			token:       gofakeit.UUID(),
+			password:    "",
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name:        "Unknown token",
+			token:       gofakeit.UUID(),
+			password:    gofakeit.Password(true, true, true, true, true, passDefaultLen),
+			expectedErr: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+				Token:    tt.token,
+				Password: tt.password,
+			})
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedErr, status.Code(err))
+		})
+	}
+}
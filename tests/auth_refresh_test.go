@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/tests/suit"
+	"testing"
+)
+
+func TestRefresh_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, resLogin.GetRefreshToken())
+
+	resRefresh, err := st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: resLogin.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, resRefresh.GetToken())
+	assert.NotEmpty(t, resRefresh.GetRefreshToken())
+	assert.NotEqual(t, resLogin.GetRefreshToken(), resRefresh.GetRefreshToken())
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	resRefresh, err := st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: resLogin.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: resLogin.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "refresh token reuse detected")
+
+	// The token issued by the legitimate rotation belongs to the revoked family.
+	_, err = st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: resRefresh.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid refresh token")
+}
+
+func TestRefresh_FailCases(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		expectedErr  string
+	}{
+		{
+			name:         "Refresh with Empty Token",
+			refreshToken: "",
+			expectedErr:  "refresh_token required",
+		},
+		{
+			name:         "Refresh with Unknown Token",
+			refreshToken: gofakeit.UUID(),
+			expectedErr:  "invalid refresh token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+				RefreshToken: tt.refreshToken,
+			})
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"sso/tests/suit"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -19,6 +20,15 @@ const (
 	passDefaultLen = 10
 )
 
+// The test app with its own token settings.
+const (
+	settingsAppID       = 3
+	settingsAppSecret   = "token-settings-secret"
+	settingsAppTokenTTL = 5 * time.Minute
+	settingsAppIssuer   = "https://issuer.sso.test"
+	settingsAppAudience = "token-settings-api"
+)
+
 func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	ctx, st := suit.NewSuit(t)
 
@@ -46,9 +56,7 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 
 	require.NotEmpty(t, token)
 
-	tokenParsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		return []byte(appSecret), nil
-	})
+	tokenParsed, err := jwt.Parse(token, tokenKey(ctx, t, st, appSecret))
 
 	require.NoError(t, err)
 
@@ -64,6 +72,103 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	assert.InDelta(t, loginTime.Add(st.Cfg.TokenTTL).Unix(), claims["exp"].(float64), deltaSeconds)
 }
 
+func TestRegisterLogin_Login_RegisteredClaims(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	loginTime := time.Now()
+
+	tokenParsed, err := jwt.Parse(resLogin.GetToken(), tokenKey(ctx, t, st, appSecret),
+		jwt.WithIssuer(st.Cfg.Tokens.Issuer),
+		jwt.WithAudience(strconv.Itoa(appID)),
+		jwt.WithIssuedAt(),
+	)
+	require.NoError(t, err)
+
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+
+	sub, err := claims.GetSubject()
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatInt(resReg.GetUserId(), 10), sub)
+	assert.Equal(t, strconv.Itoa(appID), claims["azp"])
+	assert.NotEmpty(t, claims["jti"])
+
+	const deltaSeconds = 1
+
+	assert.InDelta(t, loginTime.Unix(), claims["iat"].(float64), deltaSeconds)
+	assert.InDelta(t, loginTime.Unix(), claims["nbf"].(float64), deltaSeconds)
+}
+
+func TestRegisterLogin_Login_AppTokenSettings(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	login := func(app int32, secret string, opts ...jwt.ParserOption) jwt.MapClaims {
+		resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Email:    email,
+			Password: password,
+			AppId:    app,
+		})
+		require.NoError(t, err)
+
+		tokenParsed, err := jwt.Parse(resLogin.GetToken(), tokenKey(ctx, t, st, secret), opts...)
+		require.NoError(t, err)
+
+		claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+		require.True(t, ok)
+
+		return claims
+	}
+
+	const deltaSeconds = 1
+
+	loginTime := time.Now()
+	claims := login(settingsAppID, settingsAppSecret,
+		jwt.WithIssuer(settingsAppIssuer),
+		jwt.WithAudience(settingsAppAudience),
+	)
+
+	assert.InDelta(t, loginTime.Add(settingsAppTokenTTL).Unix(), claims["exp"].(float64), deltaSeconds)
+	assert.Equal(t, false, claims["is_admin"])
+	assert.NotContains(t, claims, "email")
+	assert.NotContains(t, claims, "roles")
+
+	// An app without settings gets the service defaults.
+	loginTime = time.Now()
+	claims = login(appID, appSecret,
+		jwt.WithIssuer(st.Cfg.Tokens.Issuer),
+		jwt.WithAudience(strconv.Itoa(appID)),
+	)
+
+	assert.InDelta(t, loginTime.Add(st.Cfg.TokenTTL).Unix(), claims["exp"].(float64), deltaSeconds)
+	assert.Equal(t, email, claims["email"])
+	assert.NotContains(t, claims, "is_admin")
+}
+
 func TestRegisterLogin_RepeatedRegistration(t *testing.T) {
 	ctx, st := suit.NewSuit(t)
 
@@ -123,9 +228,7 @@ func TestRegisterLogin_LoginAfterRepeatedRegistration(t *testing.T) {
 	token := resLogin.GetToken()
 	require.NotEmpty(t, token)
 
-	tokenParsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		return []byte(appSecret), nil
-	})
+	tokenParsed, err := jwt.Parse(token, tokenKey(ctx, t, st, appSecret))
 
 	require.NoError(t, err)
 
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"path/filepath"
+	"sort"
+	"sso/tests/suit"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// verifiedAppID only admits users with a verified email.
+	verifiedAppID = 2
+	// emailTimeout bounds the wait for emails sent in the background.
+	emailTimeout = 5 * time.Second
+)
+
+func TestVerifyEmail_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	// Apps that do not require a verified email admit the user at once.
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    verifiedAppID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{
+		Token: emailedToken(t, st, email, "verify your email address"),
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    verifiedAppID,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resLogin.GetToken())
+}
+
+func TestSendVerificationEmail(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	first := emailedToken(t, st, email, "verify your email address")
+
+	_, err = st.AuthClient.SendVerificationEmail(ctx, &ssov1.SendVerificationEmailRequest{Email: email})
+	require.NoError(t, err)
+
+	resent := emailedTokenOtherThan(t, st, email, "verify your email address", first)
+
+	_, err = st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: resent})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    verifiedAppID,
+	})
+	require.NoError(t, err)
+
+	// The response is the same for unknown and already verified emails.
+	_, err = st.AuthClient.SendVerificationEmail(ctx, &ssov1.SendVerificationEmailRequest{Email: email})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.SendVerificationEmail(ctx, &ssov1.SendVerificationEmailRequest{Email: gofakeit.Email()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.SendVerificationEmail(ctx, &ssov1.SendVerificationEmailRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestVerifyEmail_TokenReuse(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email, _, _ := registerAndLogin(ctx, t, st)
+	token := emailedToken(t, st, email, "verify your email address")
+
+	_, err := st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: token})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: token})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr codes.Code
+	}{
+		{
+			name:        "Empty token",
+			token:       "",
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name:        "Unknown token",
+			token:       gofakeit.UUID(),
+			expectedErr: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: tt.token})
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedErr, status.Code(err))
+		})
+	}
+}
+
+// emailedToken waits for an email to email that asks to use a token to do
+// action and returns the token of the last one.
+func emailedToken(t *testing.T, st *suit.Suit, email, action string) string {
+	t.Helper()
+
+	return emailedTokenOtherThan(t, st, email, action, "")
+}
+
+// emailedTokenOtherThan is emailedToken for emails sent after the one with
+// token previous.
+func emailedTokenOtherThan(t *testing.T, st *suit.Suit, email, action, previous string) string {
+	t.Helper()
+
+	var token string
+	require.Eventually(t, func() bool {
+		token = lastEmailedToken(t, st, email, action)
+		return token != "" && token != previous
+	}, emailTimeout, emailTimeout/50, "no email to %s to %s", email, action)
+
+	return token
+}
+
+// lastEmailedToken reads the token from the last email the file mailer wrote
+// for email that asks to use it to do action, if there is one.
+func lastEmailedToken(t *testing.T, st *suit.Suit, email, action string) string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(st.Cfg.Mail.Dir, "*-"+email+".eml"))
+	require.NoError(t, err)
+	sort.Strings(files)
+
+	for i := len(files) - 1; i >= 0; i-- {
+		msg, err := os.ReadFile(files[i])
+		require.NoError(t, err)
+
+		_, body, ok := strings.Cut(string(msg), "Use this code to "+action+":")
+		if !ok {
+			continue
+		}
+
+		return strings.TrimSpace(body)
+	}
+
+	return ""
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
+	"sso/tests/suit"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntrospect_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	loginTime := time.Now()
+
+	resIntrospect, err := st.AuthClient.Introspect(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.IntrospectRequest{
+		Token: resLogin.GetToken(),
+	})
+	require.NoError(t, err)
+
+	const deltaSeconds = 1
+
+	assert.True(t, resIntrospect.GetActive())
+	assert.Equal(t, resReg.GetUserId(), resIntrospect.GetUid())
+	assert.Equal(t, email, resIntrospect.GetEmail())
+	assert.Equal(t, int32(appID), resIntrospect.GetAppId())
+	assert.InDelta(t, loginTime.Add(st.Cfg.TokenTTL).Unix(), resIntrospect.GetExp(), deltaSeconds)
+
+	_, err = st.AuthClient.Logout(ctx, &ssov1.LogoutRequest{
+		Token: resLogin.GetToken(),
+	})
+	require.NoError(t, err)
+
+	resIntrospect, err = st.AuthClient.Introspect(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.IntrospectRequest{
+		Token: resLogin.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.False(t, resIntrospect.GetActive())
+	assert.Empty(t, resIntrospect.GetUid())
+}
+
+func TestIntrospect_InvalidToken(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	resIntrospect, err := st.AuthClient.Introspect(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.IntrospectRequest{
+		Token: gofakeit.UUID(),
+	})
+	require.NoError(t, err)
+	assert.False(t, resIntrospect.GetActive())
+}
+
+func TestIntrospect_HTTP(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	form := url.Values{"token": {resLogin.GetToken()}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, st.HTTPURL("/introspect"), strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(strconv.Itoa(appID), appSecret)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var body struct {
+		Active bool   `json:"active"`
+		UID    int64  `json:"uid"`
+		Email  string `json:"email"`
+		AppID  int    `json:"app_id"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+
+	assert.True(t, body.Active)
+	assert.Equal(t, resReg.GetUserId(), body.UID)
+	assert.Equal(t, email, body.Email)
+	assert.Equal(t, appID, body.AppID)
+}
+
+func TestIntrospect_Scopes(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    settingsAppID,
+	})
+	require.NoError(t, err)
+
+	resIntrospect, err := st.AuthClient.Introspect(suit.WithAppCredentials(ctx, settingsAppID, settingsAppSecret), &ssov1.IntrospectRequest{
+		Token: resLogin.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, resIntrospect.GetActive())
+	assert.Equal(t, []string{"reports:read", "reports:write"}, resIntrospect.GetScopes())
+
+	form := url.Values{"token": {resLogin.GetToken()}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, st.HTTPURL("/introspect"), strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(strconv.Itoa(settingsAppID), settingsAppSecret)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var body struct {
+		Active bool   `json:"active"`
+		Scope  string `json:"scope"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+
+	assert.True(t, body.Active)
+	assert.Equal(t, "reports:read reports:write", body.Scope)
+}
+
+func TestIntrospect_RequiresAppCredentials(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	_, err := st.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{
+		Token: gofakeit.UUID(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "app credentials required")
+
+	_, err = st.AuthClient.Introspect(suit.WithAppCredentials(ctx, appID, "wrong-secret"), &ssov1.IntrospectRequest{
+		Token: gofakeit.UUID(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid app credentials")
+
+	form := url.Values{"token": {gofakeit.UUID()}}
+
+	res, err := http.PostForm(st.HTTPURL("/introspect"), form)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestIsAdmin_RequiresAppCredentials(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	adminCtx := loginAdmin(ctx, t, st)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	req := &ssov1.IsAdminRequest{UserId: resReg.GetUserId()}
+
+	_, err = st.AuthClient.IsAdmin(ctx, req)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.ErrorContains(t, err, "app credentials required")
+
+	_, err = st.AuthClient.IsAdmin(suit.WithAppCredentials(ctx, appID, "wrong-secret"), req)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	// A bearer token is only accepted from an admin.
+	_, err = st.AuthClient.IsAdmin(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+resLogin.GetToken()), req)
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resIsAdmin, err := st.AuthClient.IsAdmin(suit.WithAppCredentials(ctx, appID, appSecret), req)
+	require.NoError(t, err)
+	assert.False(t, resIsAdmin.GetIsAdmin())
+
+	_, err = st.AdminClient.SetAdmin(adminCtx, &ssov1.SetAdminRequest{
+		UserId:  resReg.GetUserId(),
+		IsAdmin: true,
+	})
+	require.NoError(t, err)
+
+	resIsAdmin, err = st.AuthClient.IsAdmin(adminCtx, req)
+	require.NoError(t, err)
+	assert.True(t, resIsAdmin.GetIsAdmin())
+}
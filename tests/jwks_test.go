@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"math/big"
+	"net/http"
+	"sso/internal/lib/jwk"
+	"sso/tests/suit"
+	"testing"
+)
+
+func TestJWKS_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, st.HTTPURL("/.well-known/jwks.json"), nil)
+	require.NoError(t, err)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&set))
+	require.NotNil(t, set.Keys)
+
+	for _, key := range set.Keys {
+		assert.NotEmpty(t, key["kid"])
+		assert.Equal(t, "sig", key["use"])
+	}
+}
+
+func TestRotateSigningKey_RequiresAdmin(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	_, err := st.AuthClient.RotateSigningKey(ctx, &ssov1.RotateSigningKeyRequest{})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "bearer token required")
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err = st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+resLogin.GetToken())
+
+	_, err = st.AuthClient.RotateSigningKey(userCtx, &ssov1.RotateSigningKeyRequest{})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestJWKS_ManagedKeys_SignsWithPublishedKey(t *testing.T) {
+	ctx, st := managedKeysSuit(t)
+
+	_, _, token := registerAndLogin(ctx, t, st)
+
+	published := fetchJWKS(ctx, t, st)
+	require.NotEmpty(t, published)
+
+	kid := verifyWithJWKS(t, st, token, published)
+	assert.NotEmpty(t, kid)
+}
+
+func TestJWKS_ManagedKeys_Rotation(t *testing.T) {
+	ctx, st := managedKeysSuit(t)
+
+	adminCtx := loginAdmin(ctx, t, st)
+
+	_, _, oldToken := registerAndLogin(ctx, t, st)
+
+	before := fetchJWKS(ctx, t, st)
+	oldKid := verifyWithJWKS(t, st, oldToken, before)
+
+	_, err := st.AuthClient.RotateSigningKey(adminCtx, &ssov1.RotateSigningKeyRequest{})
+	require.NoError(t, err)
+
+	_, _, newToken := registerAndLogin(ctx, t, st)
+
+	after := fetchJWKS(ctx, t, st)
+	newKid := verifyWithJWKS(t, st, newToken, after)
+	require.NotEqual(t, oldKid, newKid)
+
+	// The key that took over was published while it was still pending.
+	assert.Contains(t, before, newKid)
+
+	// The retiring key stays published, so tokens it signed still verify,
+	// and the next pending key is published already.
+	assert.Contains(t, after, oldKid)
+	verifyWithJWKS(t, st, oldToken, after)
+
+	var next []string
+	for kid := range after {
+		if _, ok := before[kid]; !ok {
+			next = append(next, kid)
+		}
+	}
+	assert.Len(t, next, 1)
+
+	resIntrospect, err := st.AuthClient.Introspect(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.IntrospectRequest{
+		Token: oldToken,
+	})
+	require.NoError(t, err)
+	assert.True(t, resIntrospect.GetActive())
+}
+
+// managedKeysSuit is suit.NewSuit for tests of managed signing keys. They are
+// skipped unless the server under test signs tokens with RS256, ES256 or
+// EdDSA.
+func managedKeysSuit(t *testing.T) (context.Context, *suit.Suit) {
+	ctx, st := suit.NewSuit(t)
+
+	if st.Cfg.Signing.Algorithm == "HS256" {
+		t.Skip("tokens are signed with app secrets; run the suite with SSO_TEST_CONFIG=config/es256.yaml")
+	}
+
+	return ctx, st
+}
+
+// fetchJWKS returns the published keys by kid.
+func fetchJWKS(ctx context.Context, t *testing.T, st *suit.Suit) map[string]crypto.PublicKey {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, st.HTTPURL("/.well-known/jwks.json"), nil)
+	require.NoError(t, err)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var set jwk.Set
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&set))
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		assert.Equal(t, st.Cfg.Signing.Algorithm, key.Alg)
+
+		pub, err := publicKey(key)
+		require.NoError(t, err, key.Kid)
+		keys[key.Kid] = pub
+	}
+
+	return keys
+}
+
+// verifyWithJWKS verifies token the way a relying app would, with one of the
+// published keys, and returns the kid it was signed with.
+func verifyWithJWKS(t *testing.T, st *suit.Suit, token string, published map[string]crypto.PublicKey) string {
+	t.Helper()
+
+	parsed, err := jwt.Parse(token, publishedKey(published), jwt.WithValidMethods([]string{st.Cfg.Signing.Algorithm}))
+	require.NoError(t, err)
+
+	kid, _ := parsed.Header["kid"].(string)
+
+	return kid
+}
+
+// tokenKey returns a key function that verifies tokens the way a relying app
+// does: with its secret when the server signs tokens with HS256, and with the
+// published key named by the kid header otherwise.
+func tokenKey(ctx context.Context, t *testing.T, st *suit.Suit, secret string) jwt.Keyfunc {
+	t.Helper()
+
+	if st.Cfg.Signing.Algorithm == "HS256" {
+		return func(*jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		}
+	}
+
+	return publishedKey(fetchJWKS(ctx, t, st))
+}
+
+func publishedKey(published map[string]crypto.PublicKey) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := published[kid]
+		if !ok {
+			return nil, fmt.Errorf("kid %q is not published", kid)
+		}
+
+		return key, nil
+	}
+}
+
+func publicKey(key jwk.Key) (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch key.Kty {
+	case "RSA":
+		n, err := decode(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(key.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		x, err := decode(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := decode(key.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", key.Kty)
+	}
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"sso/tests/suit"
+	"testing"
+)
+
+func TestHasPermission_NoRoles(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: gofakeit.Password(true, true, true, true, true, passDefaultLen),
+	})
+	require.NoError(t, err)
+
+	resHas, err := st.AuthClient.HasPermission(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.HasPermissionRequest{
+		UserId:     resReg.GetUserId(),
+		AppId:      appID,
+		Permission: "articles:write",
+	})
+	require.NoError(t, err)
+	assert.False(t, resHas.GetHasPermission())
+}
+
+func TestRBAC_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	adminCtx := loginAdmin(ctx, t, st)
+	appCtx := suit.WithAppCredentials(ctx, appID, appSecret)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	// Roles are shared by the whole app, so every run uses its own.
+	role := "editor-" + gofakeit.UUID()
+	permission := "articles:write:" + gofakeit.UUID()
+
+	hasPermission := func(permission string) bool {
+		resHas, err := st.AuthClient.HasPermission(appCtx, &ssov1.HasPermissionRequest{
+			UserId:     resReg.GetUserId(),
+			AppId:      appID,
+			Permission: permission,
+		})
+		require.NoError(t, err)
+
+		return resHas.GetHasPermission()
+	}
+
+	_, err = st.AuthClient.AssignRole(adminCtx, &ssov1.AssignRoleRequest{
+		UserId: resReg.GetUserId(),
+		AppId:  appID,
+		Role:   role,
+	})
+	require.NoError(t, err)
+
+	assert.False(t, hasPermission(permission))
+
+	_, err = st.AuthClient.GrantPermission(adminCtx, &ssov1.GrantPermissionRequest{
+		AppId:      appID,
+		Role:       role,
+		Permission: permission,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, hasPermission(permission))
+	assert.False(t, hasPermission("articles:delete:"+gofakeit.UUID()))
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	tokenParsed, err := jwt.Parse(resLogin.GetToken(), tokenKey(ctx, t, st, appSecret))
+	require.NoError(t, err)
+
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{role}, claims["roles"])
+
+	_, err = st.AuthClient.RevokeRole(adminCtx, &ssov1.RevokeRoleRequest{
+		UserId: resReg.GetUserId(),
+		AppId:  appID,
+		Role:   role,
+	})
+	require.NoError(t, err)
+
+	assert.False(t, hasPermission(permission))
+}
+
+func TestAssignRole_RequiresAdmin(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+resLogin.GetToken())
+
+	_, err = st.AuthClient.AssignRole(userCtx, &ssov1.AssignRoleRequest{
+		UserId: resReg.GetUserId(),
+		AppId:  appID,
+		Role:   "editor",
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestRBAC_FailCases(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	tests := []struct {
+		name        string
+		userID      int64
+		appID       int32
+		permission  string
+		expectedErr string
+	}{
+		{
+			name:        "HasPermission without UserID",
+			userID:      0,
+			appID:       appID,
+			permission:  "articles:write",
+			expectedErr: "user_id required",
+		},
+		{
+			name:        "HasPermission without AppID",
+			userID:      1,
+			appID:       emptyAppID,
+			permission:  "articles:write",
+			expectedErr: "app required",
+		},
+		{
+			name:        "HasPermission without Permission",
+			userID:      1,
+			appID:       appID,
+			permission:  "",
+			expectedErr: "permission required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.HasPermission(suit.WithAppCredentials(ctx, appID, appSecret), &ssov1.HasPermissionRequest{
+				UserId:     tt.userID,
+				AppId:      tt.appID,
+				Permission: tt.permission,
+			})
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
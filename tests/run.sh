@@ -0,0 +1,69 @@
+#!/bin/sh
+# Runs the integration tests once per server config. Each run starts the
+# server with tests/config/NAME.yaml and points the tests at it through
+# SSO_TEST_CONFIG. Run it from the repository root:
+#
+#	tests/run.sh                  all configs
+#	tests/run.sh memory es256     only the named ones
+#
+# The local config needs the Postgres database it names; sqlite recreates
+# tests/sso.db. The memory, rs256, es256 and eddsa configs need nothing.
+set -eu
+
+configs=${*:-local sqlite memory rs256 es256 eddsa}
+
+bin=$(mktemp -d)
+server=
+cleanup() {
+	if [ -n "$server" ]; then
+		kill "$server" 2>/dev/null || true
+		wait "$server" 2>/dev/null || true
+	fi
+	rm -rf "$bin"
+}
+trap cleanup EXIT
+trap 'exit 1' INT TERM
+
+go build -o "$bin/sso" ./cmd/sso
+go build -o "$bin/migrator" ./cmd/migrator
+
+failed=
+for name in $configs; do
+	config=tests/config/$name.yaml
+
+	case $name in
+	local)
+		"$bin/migrator" --config "$config" --path tests/test_migrations up
+		;;
+	sqlite)
+		rm -f tests/sso.db
+		"$bin/migrator" --config "$config" --path tests/test_migrations/sqlite up
+		;;
+	esac
+
+	"$bin/sso" --config "$config" >"$bin/$name.log" 2>&1 &
+	server=$!
+
+	tries=0
+	until curl -sf -o /dev/null http://localhost:8080/.well-known/jwks.json; do
+		tries=$((tries + 1))
+		if [ "$tries" -ge 50 ] || ! kill -0 "$server" 2>/dev/null; then
+			echo "$name: server did not start:" >&2
+			cat "$bin/$name.log" >&2
+			exit 1
+		fi
+		sleep 0.2
+	done
+
+	echo "== $name"
+	SSO_TEST_CONFIG=config/$name.yaml go test -count=1 ./tests || failed="$failed $name"
+
+	kill "$server"
+	wait "$server" 2>/dev/null || true
+	server=
+done
+
+if [ -n "$failed" ]; then
+	echo "failed:$failed" >&2
+	exit 1
+fi
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/gffone/protos/gen/go/sso"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/tests/suit"
+	"testing"
+)
+
+func TestExchangeSession_HappyPath(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	resReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, resLogin.GetSessionToken())
+
+	resExchange, err := st.AuthClient.ExchangeSession(ctx, &ssov1.ExchangeSessionRequest{
+		SessionToken: resLogin.GetSessionToken(),
+		AppId:        appID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, resExchange.GetToken())
+	assert.NotEmpty(t, resExchange.GetRefreshToken())
+
+	tokenParsed, err := jwt.Parse(resExchange.GetToken(), tokenKey(ctx, t, st, appSecret))
+	require.NoError(t, err)
+
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	assert.Equal(t, resReg.GetUserId(), int64(claims["uid"].(float64)))
+	assert.Equal(t, appID, int(claims["app_id"].(float64)))
+}
+
+func TestExchangeSession_AfterLogout(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	email := gofakeit.Email()
+	password := gofakeit.Password(true, true, true, true, true, passDefaultLen)
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	resLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Logout(ctx, &ssov1.LogoutRequest{
+		Token:        resLogin.GetToken(),
+		SessionToken: resLogin.GetSessionToken(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ExchangeSession(ctx, &ssov1.ExchangeSessionRequest{
+		SessionToken: resLogin.GetSessionToken(),
+		AppId:        appID,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid session")
+}
+
+func TestExchangeSession_FailCases(t *testing.T) {
+	ctx, st := suit.NewSuit(t)
+
+	tests := []struct {
+		name         string
+		sessionToken string
+		appID        int32
+		expectedErr  string
+	}{
+		{
+			name:         "Exchange with Empty Session",
+			sessionToken: "",
+			appID:        appID,
+			expectedErr:  "session_token required",
+		},
+		{
+			name:         "Exchange without AppID",
+			sessionToken: gofakeit.UUID(),
+			appID:        emptyAppID,
+			expectedErr:  "app required",
+		},
+		{
+			name:         "Exchange with Unknown Session",
+			sessionToken: gofakeit.UUID(),
+			appID:        appID,
+			expectedErr:  "invalid session",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.ExchangeSession(ctx, &ssov1.ExchangeSessionRequest{
+				SessionToken: tt.sessionToken,
+				AppId:        tt.appID,
+			})
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/brianvoe/gofakeit/v6"
+	"sso/internal/config"
+	"sso/internal/lib/envelope"
+	"sso/internal/storage/postgres"
+	"sso/tests/suit"
+	"sync/atomic"
+	"testing"
+)
+
+// The benchmarks compare preparing statements once, as the storage does,
+// with the previous approach of preparing a statement on every call, under
+// concurrent load. Run them against the test database with
+//
+//	go test ./tests -run '^$' -bench Storage -cpu 1,8,32
+
+const selectIDQuery = "SELECT id FROM users WHERE email = $1"
+
+func BenchmarkStorage_User(b *testing.B) {
+	ctx := context.Background()
+	st, db := newBenchStorage(b)
+
+	email := gofakeit.Email()
+	if _, err := st.SaveUser(ctx, email, []byte("hash")); err != nil {
+		b.Fatal(err)
+	}
+
+	// Both cases run the same query; they differ only in when it is
+	// prepared.
+	stmt, err := db.PrepareContext(ctx, selectIDQuery)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { stmt.Close() })
+
+	b.Run("prepared", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := selectID(ctx, stmt, email); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+
+	b.Run("prepare_per_call", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := selectIDPerCall(ctx, db, email); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkStorage_SaveUser(b *testing.B) {
+	ctx := context.Background()
+	st, db := newBenchStorage(b)
+
+	prefix := gofakeit.LetterN(8)
+	var n atomic.Int64
+
+	nextEmail := func() string {
+		return fmt.Sprintf("bench-%s-%d@example.com", prefix, n.Add(1))
+	}
+
+	b.Run("insert_returning", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := st.SaveUser(ctx, nextEmail(), []byte("hash")); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+
+	// check_insert_select replays the former SaveUser: an existence check,
+	// the INSERT and a SELECT of the new ID, each prepared on the spot.
+	b.Run("check_insert_select", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				email := nextEmail()
+
+				_, err := selectIDPerCall(ctx, db, email)
+				if !errors.Is(err, sql.ErrNoRows) {
+					b.Errorf("existence check: %v", err)
+					continue
+				}
+
+				stmt, err := db.PrepareContext(ctx, "INSERT INTO users (email, pass_hash) VALUES ($1, $2)")
+				if err != nil {
+					b.Error(err)
+					continue
+				}
+				_, err = stmt.ExecContext(ctx, email, []byte("hash"))
+				stmt.Close()
+				if err != nil {
+					b.Error(err)
+					continue
+				}
+
+				if _, err := selectIDPerCall(ctx, db, email); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
+
+// selectID selects the ID of the user with email with stmt, a prepared
+// selectIDQuery.
+func selectID(ctx context.Context, stmt *sql.Stmt, email string) (int64, error) {
+	var id int64
+	err := stmt.QueryRowContext(ctx, email).Scan(&id)
+
+	return id, err
+}
+
+// selectIDPerCall prepares selectIDQuery, runs it and closes it again.
+func selectIDPerCall(ctx context.Context, db *sql.DB, email string) (int64, error) {
+	stmt, err := db.PrepareContext(ctx, selectIDQuery)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	return selectID(ctx, stmt, email)
+}
+
+func newBenchStorage(b *testing.B) (*postgres.Storage, *sql.DB) {
+	b.Helper()
+
+	cfg := config.MustLoadPath(suit.ConfigPath())
+
+	keyring, err := envelope.LoadKeyring(cfg.Encryption.MasterKey, cfg.Encryption.MasterKeyFile, cfg.Encryption.PreviousMasterKeys)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	dsn, err := cfg.Storage.URL()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pool := postgres.PoolConfig{
+		MaxOpenConns:    cfg.Storage.MaxOpenConns,
+		MaxIdleConns:    cfg.Storage.MaxIdleConns,
+		ConnMaxLifetime: cfg.Storage.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Storage.ConnMaxIdleTime,
+	}
+
+	st, err := postgres.New(dsn, pool, keyring)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { st.Close() })
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	b.Cleanup(func() { db.Close() })
+
+	return st, db
+}
@@ -5,7 +5,9 @@ import (
 	ssov1 "github.com/gffone/protos/gen/go/sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"net"
+	"os"
 	"sso/internal/config"
 	"strconv"
 	"testing"
@@ -13,12 +15,28 @@ import (
 
 const (
 	grpcHost = "localhost"
+	// configPathEnv names the environment variable that selects the config
+	// of the server under test, such as config/es256.yaml for a server that
+	// signs tokens with managed keys. Paths are relative to the tests
+	// directory. tests/run.sh runs the tests once per config.
+	configPathEnv     = "SSO_TEST_CONFIG"
+	defaultConfigPath = "config/local.yaml"
 )
 
+// ConfigPath returns the path of the config the server under test runs with.
+func ConfigPath() string {
+	if path := os.Getenv(configPathEnv); path != "" {
+		return path
+	}
+
+	return defaultConfigPath
+}
+
 type Suit struct {
 	*testing.T
-	Cfg        *config.Config
-	AuthClient ssov1.AuthClient
+	Cfg         *config.Config
+	AuthClient  ssov1.AuthClient
+	AdminClient ssov1.AdminClient
 }
 
 func NewSuit(t *testing.T) (context.Context, *Suit) {
@@ -26,7 +44,7 @@ func NewSuit(t *testing.T) (context.Context, *Suit) {
 	t.Parallel()
 
 	ctx := context.Background()
-	cfg := config.MustLoad()
+	cfg := config.MustLoadPath(ConfigPath())
 	ctx, cancelCtx := context.WithTimeout(context.Background(), cfg.GRPC.Timeout)
 
 	t.Cleanup(func() {
@@ -40,9 +58,10 @@ func NewSuit(t *testing.T) (context.Context, *Suit) {
 	}
 
 	return ctx, &Suit{
-		T:          t,
-		Cfg:        cfg,
-		AuthClient: ssov1.NewAuthClient(cc),
+		T:           t,
+		Cfg:         cfg,
+		AuthClient:  ssov1.NewAuthClient(cc),
+		AdminClient: ssov1.NewAdminClient(cc),
 	}
 
 }
@@ -50,3 +69,17 @@ func NewSuit(t *testing.T) (context.Context, *Suit) {
 func grpcAddr(cfg *config.Config) string {
 	return net.JoinHostPort(grpcHost, strconv.Itoa(cfg.GRPC.Port))
 }
+
+// HTTPURL returns the URL of path on the service's HTTP server.
+func (s *Suit) HTTPURL(path string) string {
+	return "http://" + net.JoinHostPort(grpcHost, strconv.Itoa(s.Cfg.HTTP.Port)) + path
+}
+
+// WithAppCredentials attaches app credentials to outgoing gRPC calls made
+// with ctx.
+func WithAppCredentials(ctx context.Context, appID int, secret string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		"x-app-id", strconv.Itoa(appID),
+		"x-app-secret", secret,
+	)
+}
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS data_keys
+(
+    id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    wrapped_key   BYTEA       NOT NULL,
+    master_key_id TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_data_keys_master_key_id ON data_keys (master_key_id);
+
+-- Rows without a data key are still plaintext; the rekey command encrypts
+-- them.
+ALTER TABLE app_secrets
+    DROP CONSTRAINT IF EXISTS app_secrets_secret_key,
+    ALTER COLUMN secret TYPE BYTEA USING convert_to(secret, 'UTF8'),
+    ADD COLUMN data_key_id BIGINT REFERENCES data_keys (id);
+
+ALTER TABLE signing_keys
+    ADD COLUMN data_key_id BIGINT REFERENCES data_keys (id);
@@ -0,0 +1,3 @@
+ALTER TABLE apps
+    DROP COLUMN scopes,
+    DROP COLUMN token_ttl_seconds;
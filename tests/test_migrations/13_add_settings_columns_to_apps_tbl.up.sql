@@ -0,0 +1,4 @@
+-- scopes is space-separated, as in OAuth 2.0.
+ALTER TABLE apps
+    ADD COLUMN token_ttl_seconds BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN scopes            TEXT   NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE apps
+    DROP COLUMN claims,
+    DROP COLUMN audience,
+    DROP COLUMN issuer;
@@ -0,0 +1,5 @@
+-- claims is a space-separated list; NULL means the default claims.
+ALTER TABLE apps
+    ADD COLUMN issuer   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN audience TEXT NOT NULL DEFAULT '',
+    ADD COLUMN claims   TEXT;
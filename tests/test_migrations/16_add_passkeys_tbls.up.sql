@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS passkeys
+(
+    id               BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id          BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    credential_id    BYTEA       NOT NULL UNIQUE,
+    public_key       BYTEA       NOT NULL,
+    attestation_type TEXT        NOT NULL,
+    aaguid           BYTEA       NOT NULL,
+    sign_count       BIGINT      NOT NULL DEFAULT 0,
+    transports       TEXT        NOT NULL DEFAULT '',
+    backup_eligible  BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_passkeys_user_id ON passkeys (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies
+(
+    token_hash BYTEA PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id     BIGINT REFERENCES apps (id) ON DELETE CASCADE,
+    session    BYTEA       NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webauthn_ceremonies_user_id ON webauthn_ceremonies (user_id);
@@ -0,0 +1,26 @@
+-- Users that exist before verification is introduced count as verified, so
+-- that apps which start to require a verified email do not lock them out.
+-- Users registered from now on start unverified.
+ALTER TABLE users
+    ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE users
+    ALTER COLUMN email_verified SET DEFAULT FALSE;
+
+ALTER TABLE apps
+    ADD COLUMN require_verified_email BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS email_verifications
+(
+    token_hash BYTEA PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email      TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications (user_id);
+
+-- A second test app that refuses logins of users with an unverified email.
+INSERT INTO apps (id, name, require_verified_email) VALUES (2, 'verified', TRUE)
+ON CONFLICT DO NOTHING;
+INSERT INTO app_secrets (app_id, secret, state)
+SELECT 2, convert_to('verified-secret', 'UTF8'), 'active'
+WHERE NOT EXISTS (SELECT 1 FROM app_secrets WHERE app_id = 2);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS password_resets
+(
+    token_hash BYTEA PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
@@ -0,0 +1,8 @@
+-- A third test app with its own token TTL, scopes, issuer, audience and
+-- claims.
+INSERT INTO apps (id, name, token_ttl_seconds, scopes, issuer, audience, claims)
+VALUES (3, 'token-settings', 300, 'reports:read reports:write', 'https://issuer.sso.test', 'token-settings-api', 'is_admin')
+ON CONFLICT DO NOTHING;
+INSERT INTO app_secrets (app_id, secret, state)
+SELECT 3, convert_to('token-settings-secret', 'UTF8'), 'active'
+WHERE NOT EXISTS (SELECT 1 FROM app_secrets WHERE app_id = 3);
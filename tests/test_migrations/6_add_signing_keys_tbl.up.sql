@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS signing_keys
+(
+    kid         TEXT PRIMARY KEY,
+    algorithm   TEXT        NOT NULL,
+    private_key BYTEA       NOT NULL,
+    public_key  BYTEA       NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_signing_keys_state;
+ALTER TABLE signing_keys
+    DROP COLUMN state,
+    DROP COLUMN activated_at,
+    DROP COLUMN retire_at;
@@ -0,0 +1,3 @@
+ALTER TABLE apps DROP COLUMN claims;
+ALTER TABLE apps DROP COLUMN audience;
+ALTER TABLE apps DROP COLUMN issuer;
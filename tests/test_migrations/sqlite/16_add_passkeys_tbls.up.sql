@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS passkeys
+(
+    id               INTEGER PRIMARY KEY,
+    user_id          INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    credential_id    BLOB    NOT NULL UNIQUE,
+    public_key       BLOB    NOT NULL,
+    attestation_type TEXT    NOT NULL,
+    aaguid           BLOB    NOT NULL,
+    sign_count       INTEGER NOT NULL DEFAULT 0,
+    transports       TEXT    NOT NULL DEFAULT '',
+    backup_eligible  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at       INTEGER NOT NULL,
+    last_used_at     INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_passkeys_user_id ON passkeys (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies
+(
+    token_hash BLOB PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id     INTEGER REFERENCES apps (id) ON DELETE CASCADE,
+    session    BLOB    NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webauthn_ceremonies_user_id ON webauthn_ceremonies (user_id);
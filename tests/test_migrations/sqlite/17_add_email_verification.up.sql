@@ -0,0 +1,25 @@
+-- Users that exist before verification is introduced count as verified, so
+-- that apps which start to require a verified email do not lock them out.
+-- Users registered from now on start unverified.
+ALTER TABLE users
+    ADD COLUMN email_verified INTEGER NOT NULL DEFAULT 0;
+UPDATE users SET email_verified = 1;
+
+ALTER TABLE apps
+    ADD COLUMN require_verified_email INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS email_verifications
+(
+    token_hash BLOB PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email      TEXT    NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications (user_id);
+
+-- A second test app that refuses logins of users with an unverified email.
+INSERT INTO apps (id, name, require_verified_email) VALUES (2, 'verified', 1)
+ON CONFLICT DO NOTHING;
+INSERT INTO app_secrets (app_id, secret, state, created_at)
+SELECT 2, CAST('verified-secret' AS BLOB), 'active', CAST(unixepoch('subsec') * 1000000000 AS INTEGER)
+WHERE NOT EXISTS (SELECT 1 FROM app_secrets WHERE app_id = 2);
@@ -0,0 +1,4 @@
+-- The admin the tests log in as; its password is admin-password.
+INSERT INTO users (email, pass_hash, is_admin, email_verified)
+VALUES ('admin@sso.test', CAST('$2a$10$2SgBv7QC5ACZ7VdQ4wSPs.mNcqqvCfPapEg0ngos0FYvlej2jv0zG' AS BLOB), 1, 1)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+INSERT INTO apps (id, name, secret) VALUES (1, 'test', 'test-secret')
+ON CONFLICT DO NOTHING;